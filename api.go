@@ -35,6 +35,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 	"github.com/decred/dcrd/dcrec/secp256k1"
 	"math/big"
 	"github.com/decred/base58"
@@ -64,15 +65,43 @@ func RandomNode() string {
 	return PublicNodes[int(b[0])%len(PublicNodes)]
 }
 
+// NodeAPI is the set of node commands used by the higher-level functions of
+// this package. API and NodePool implement it.
+type NodeAPI interface {
+	GetNodeInfo() (*GetNodeInfoResponse, error)
+	CheckConsistency(tails []Trytes) (*CheckConsistencyResponse, error)
+	GetNeighbors() (*GetNeighborsResponse, error)
+	AddNeighbors(uris []string) (*AddNeighborsResponse, error)
+	RemoveNeighbors(uris []string) (*RemoveNeighborsResponse, error)
+	GetTips() (*GetTipsResponse, error)
+	FindTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error)
+	GetTrytes(hashes []Trytes) (*GetTrytesResponse, error)
+	GetInclusionStates(tx []Trytes, tips []Trytes) (*GetInclusionStatesResponse, error)
+	Balances(adr []Address, seed Trytes) (Balances, error)
+	GetBalances(adr []Address, threshold int64) (*GetBalancesResponse, error)
+	GetTransactionsToApprove(depth, numWalks int64, reference Trytes) (*GetTransactionsToApproveResponse, error)
+	AttachToTangle(att *AttachToTangleRequest) (*AttachToTangleResponse, error)
+	InterruptAttachingToTangle() error
+	BroadcastTransactions(trytes []Transaction) error
+	StoreTransactions(trytes []Transaction) error
+	GetLatestInclusion(hash []Trytes) ([]bool, error)
+}
+
+var _ NodeAPI = (*API)(nil)
+
 // API is for calling APIs.
 type API struct {
-	client   *http.Client
-	endpoint string
+	client    *http.Client
+	endpoint  string
+	retry     RetryPolicy
+	batchSize int
+	workers   int
 }
 
 // NewAPI takes an (optional) endpoint and optional http.Client and returns
 // an API struct. If an empty endpoint is supplied, then "http://localhost:14265"
-// is used.
+// is used. Commands are retried according to DefaultRetryPolicy and large
+// requests are split into batches of DefaultBatchSize.
 func NewAPI(endpoint string, c *http.Client) *API {
 	if c == nil {
 		c = http.DefaultClient
@@ -82,40 +111,70 @@ func NewAPI(endpoint string, c *http.Client) *API {
 		endpoint = "http://localhost:14265/"
 	}
 
-	return &API{client: c, endpoint: endpoint}
+	return &API{
+		client:    c,
+		endpoint:  endpoint,
+		retry:     DefaultRetryPolicy,
+		batchSize: DefaultBatchSize,
+		workers:   DefaultWorkers,
+	}
 }
 
-func handleError(err *ErrorResponse, err1, err2 error) error {
-	switch {
-	case err.Error != "":
-		return errors.New(err.Error)
-	case err.Exception != "":
-		return errors.New(err.Exception)
-	case err1 != nil:
-		return err1
+// SetRetryPolicy sets the policy used to retry failed commands.
+func (api *API) SetRetryPolicy(p RetryPolicy) {
+	api.retry = p
+}
+
+func nodeError(command string, status int, bs []byte, msg string) error {
+	errResp := &ErrorResponse{}
+	if err := json.Unmarshal(bs, errResp); err != nil && status == http.StatusOK {
+		return &DecodeError{Command: command, Body: bs, Err: err}
 	}
 
-	return err2
+	switch {
+	case errResp.Error != "":
+		msg = errResp.Error
+	case errResp.Exception != "":
+		msg = errResp.Exception
+	}
+	return &NodeError{Command: command, StatusCode: status, Message: msg}
 }
 
+// do sends cmd and decodes the response into out, retrying according to the
+// retry policy of api.
 func (api *API) do(cmd interface{}, out interface{}) error {
 	b, err := json.Marshal(cmd)
 	if err != nil {
 		return err
 	}
 
-	rd := bytes.NewReader(b)
+	var c struct {
+		Command string `json:"command"`
+	}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest("POST", api.endpoint, rd)
+	for attempt := 1; ; attempt++ {
+		err = api.send(c.Command, b, out)
+		if err == nil || attempt >= api.retry.MaxAttempts || !api.retry.Retryable(c.Command, err) {
+			return err
+		}
+		time.Sleep(api.retry.Backoff(attempt))
+	}
+}
+
+func (api *API) send(command string, b []byte, out interface{}) error {
+	req, err := http.NewRequest("POST", api.endpoint, bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-IOTA-API-Version", "1")
+	req.Header.Set("X-IOTA-API-Version", APIVersion)
 	resp, err := api.client.Do(req)
 	if err != nil {
-		return err
+		return &NetworkError{Command: command, Err: err}
 	}
 
 	defer func() {
@@ -126,25 +185,24 @@ func (api *API) do(cmd interface{}, out interface{}) error {
 
 	bs, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return &NetworkError{Command: command, Err: err}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		errResp := &ErrorResponse{}
-		err = json.Unmarshal(bs, errResp)
-		return handleError(errResp, err, fmt.Errorf("http status %d while calling API", resp.StatusCode))
+		return nodeError(command, resp.StatusCode, bs, fmt.Sprintf("http status %d while calling API", resp.StatusCode))
 	}
 
 	if bytes.Contains(bs, []byte(`"error"`)) || bytes.Contains(bs, []byte(`"exception"`)) {
-		errResp := &ErrorResponse{}
-		err = json.Unmarshal(bs, errResp)
-		return handleError(errResp, err, fmt.Errorf("unknown error occured while calling API"))
+		return nodeError(command, resp.StatusCode, bs, "unknown error occured while calling API")
 	}
 
 	if out == nil {
 		return nil
 	}
-	return json.Unmarshal(bs, out)
+	if err = json.Unmarshal(bs, out); err != nil {
+		return &DecodeError{Command: command, Body: bs, Err: err}
+	}
+	return nil
 }
 
 // ErrorResponse is for an exception occurring while calling API.
@@ -204,6 +262,12 @@ func (api *API) CheckConsistency(tails []Trytes) (*CheckConsistencyResponse, err
 	return resp, err
 }
 
+// CheckConsistencyRequest is for CheckConsistency API request.
+type CheckConsistencyRequest struct {
+	Command string   `json:"command"`
+	Tails   []Trytes `json:"tails"`
+}
+
 // CheckConsistencyResponse is for CheckConsistency API response.
 type CheckConsistencyResponse struct {
 	Duration int64  `json:"duration"`
@@ -337,8 +401,8 @@ type FindTransactionsResponse struct {
 	Hashes   []Trytes `json:"hashes"`
 }
 
-// FindTransactions calls FindTransactions API.
-func (api *API) FindTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error) {
+// findTransactions calls FindTransactions API with a single request.
+func (api *API) findTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error) {
 	resp := &FindTransactionsResponse{}
 	err := api.do(&struct {
 		Command string `json:"command"`
@@ -363,8 +427,8 @@ type GetTrytesResponse struct {
 	Trytes   []Transaction `json:"trytes"`
 }
 
-// GetTrytes calls GetTrytes API.
-func (api *API) GetTrytes(hashes []Trytes) (*GetTrytesResponse, error) {
+// getTrytes calls GetTrytes API with a single request.
+func (api *API) getTrytes(hashes []Trytes) (*GetTrytesResponse, error) {
 	resp := &GetTrytesResponse{}
 	err := api.do(&struct {
 		Command string   `json:"command"`
@@ -390,8 +454,8 @@ type GetInclusionStatesResponse struct {
 	States   []bool `json:"states"`
 }
 
-// GetInclusionStates calls GetInclusionStates API.
-func (api *API) GetInclusionStates(tx []Trytes, tips []Trytes) (*GetInclusionStatesResponse, error) {
+// getInclusionStates calls GetInclusionStates API with a single request.
+func (api *API) getInclusionStates(tx []Trytes, tips []Trytes) (*GetInclusionStatesResponse, error) {
 	resp := &GetInclusionStatesResponse{}
 	err := api.do(&struct {
 		Command      string   `json:"command"`
@@ -448,7 +512,13 @@ func (api *API) Balances(adr []Address, seed Trytes) (Balances, error) {
 		return nil, err
 	}
 
-	bs := make(Balances, 0, len(adr))
+	return decodeBalances(r, seed)
+}
+
+// decodeBalances decrypts the confidential balances of a GetBalances response
+// with the keys derived from seed.
+func decodeBalances(r *GetBalancesResponse, seed Trytes) (Balances, error) {
+	bs := make(Balances, 0, len(r.Balances))
 	for i, bal := range r.Balances {
 		trimBal := strings.Trim(bal, "[]")
 		if strings.Contains(trimBal, "0") {
@@ -503,8 +573,8 @@ func (api *API) Balances(adr []Address, seed Trytes) (Balances, error) {
 	return bs, nil
 }
 
-// GetBalances calls GetBalances API.
-func (api *API) GetBalances(adr []Address, threshold int64) (*GetBalancesResponse, error) {
+// getBalances calls GetBalances API with a single request.
+func (api *API) getBalances(adr []Address, threshold int64) (*GetBalancesResponse, error) {
 	if threshold <= 0 {
 		threshold = 100
 	}
@@ -543,8 +613,10 @@ func (api *API) GetBalances(adr []Address, threshold int64) (*GetBalancesRespons
 
 // GetTransactionsToApproveRequest is for GetTransactionsToApprove API request.
 type GetTransactionsToApproveRequest struct {
-	Command string `json:"command"`
-	Depth   int64  `json:"depth"`
+	Command   string `json:"command"`
+	Depth     int64  `json:"depth"`
+	NumWalks  int64  `json:"numWalks,omitempty"`
+	Reference Trytes `json:"reference,omitempty"`
 }
 
 // GetTransactionsToApproveResponse is for GetTransactionsToApprove API response.
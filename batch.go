@@ -0,0 +1,251 @@
+package giota
+
+import "sync"
+
+// Default values of API batching.
+const (
+	DefaultBatchSize = 500
+	DefaultWorkers   = 4
+)
+
+// SetBatching sets the maximum number of addresses, hashes, bundles or tags sent
+// in one request and the number of requests sent concurrently when a command is
+// split into batches. Values below 1 disable batching and concurrency.
+func (api *API) SetBatching(size, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	api.batchSize = size
+	api.workers = workers
+}
+
+// batches calls fn for each batch [i, j) of n items, running up to api.workers
+// calls concurrently. It returns the first error.
+func (api *API) batches(n int, fn func(i, j int) error) error {
+	size := api.batchSize
+	if size < 1 {
+		size = n
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		err  error
+		jobs = make(chan int)
+	)
+
+	for w := 0; w < api.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				j := i + size
+				if j > n {
+					j = n
+				}
+
+				if e := fn(i, j); e != nil {
+					mu.Lock()
+					if err == nil {
+						err = e
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i += size {
+		mu.Lock()
+		failed := err != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return err
+}
+
+// FindTransactions calls FindTransactions API. The longest list of ft is split
+// into batches which are sent concurrently, and the hashes found are merged
+// without duplicates.
+func (api *API) FindTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error) {
+	// a transaction matches the request if it matches all of its lists, so a
+	// request can be split on any one of them.
+	n := 0
+	split := func(i, j int) *FindTransactionsRequest { return ft }
+	if l := len(ft.Addresses); l > n {
+		n = l
+		split = func(i, j int) *FindTransactionsRequest {
+			r := *ft
+			r.Addresses = ft.Addresses[i:j]
+			return &r
+		}
+	}
+	if l := len(ft.Bundles); l > n {
+		n = l
+		split = func(i, j int) *FindTransactionsRequest {
+			r := *ft
+			r.Bundles = ft.Bundles[i:j]
+			return &r
+		}
+	}
+	if l := len(ft.Tags); l > n {
+		n = l
+		split = func(i, j int) *FindTransactionsRequest {
+			r := *ft
+			r.Tags = ft.Tags[i:j]
+			return &r
+		}
+	}
+	if l := len(ft.Approvees); l > n {
+		n = l
+		split = func(i, j int) *FindTransactionsRequest {
+			r := *ft
+			r.Approvees = ft.Approvees[i:j]
+			return &r
+		}
+	}
+
+	if api.batchSize < 1 || n <= api.batchSize {
+		return api.findTransactions(ft)
+	}
+
+	var mu sync.Mutex
+	results := make(map[int]*FindTransactionsResponse)
+	err := api.batches(n, func(i, j int) error {
+		r, err := api.findTransactions(split(i, j))
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		results[i] = r
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &FindTransactionsResponse{}
+	seen := make(map[Trytes]bool)
+	for i := 0; i < n; i += api.batchSize {
+		r := results[i]
+		if r.Duration > resp.Duration {
+			resp.Duration = r.Duration
+		}
+		for _, h := range r.Hashes {
+			if !seen[h] {
+				seen[h] = true
+				resp.Hashes = append(resp.Hashes, h)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// GetTrytes calls GetTrytes API. hashes are split into batches which are sent
+// concurrently.
+func (api *API) GetTrytes(hashes []Trytes) (*GetTrytesResponse, error) {
+	if api.batchSize < 1 || len(hashes) <= api.batchSize {
+		return api.getTrytes(hashes)
+	}
+
+	var mu sync.Mutex
+	resp := &GetTrytesResponse{Trytes: make([]Transaction, len(hashes))}
+	err := api.batches(len(hashes), func(i, j int) error {
+		r, err := api.getTrytes(hashes[i:j])
+		if err != nil {
+			return err
+		}
+		if len(r.Trytes) != j-i {
+			return &NodeError{Command: "getTrytes", Message: "node returned a wrong number of transactions"}
+		}
+
+		mu.Lock()
+		copy(resp.Trytes[i:j], r.Trytes)
+		if r.Duration > resp.Duration {
+			resp.Duration = r.Duration
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetInclusionStates calls GetInclusionStates API. tx are split into batches
+// which are sent concurrently with the same tips.
+func (api *API) GetInclusionStates(tx []Trytes, tips []Trytes) (*GetInclusionStatesResponse, error) {
+	if api.batchSize < 1 || len(tx) <= api.batchSize {
+		return api.getInclusionStates(tx, tips)
+	}
+
+	var mu sync.Mutex
+	resp := &GetInclusionStatesResponse{States: make([]bool, len(tx))}
+	err := api.batches(len(tx), func(i, j int) error {
+		r, err := api.getInclusionStates(tx[i:j], tips)
+		if err != nil {
+			return err
+		}
+		if len(r.States) != j-i {
+			return &NodeError{Command: "getInclusionStates", Message: "node returned a wrong number of states"}
+		}
+
+		mu.Lock()
+		copy(resp.States[i:j], r.States)
+		if r.Duration > resp.Duration {
+			resp.Duration = r.Duration
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetBalances calls GetBalances API. adr are split into batches which are sent
+// concurrently. Batches may be answered at different milestones; the response
+// reports the oldest one.
+func (api *API) GetBalances(adr []Address, threshold int64) (*GetBalancesResponse, error) {
+	if api.batchSize < 1 || len(adr) <= api.batchSize {
+		return api.getBalances(adr, threshold)
+	}
+
+	var mu sync.Mutex
+	resp := &GetBalancesResponse{Balances: make([]string, len(adr))}
+	err := api.batches(len(adr), func(i, j int) error {
+		r, err := api.getBalances(adr[i:j], threshold)
+		if err != nil {
+			return err
+		}
+		if len(r.Balances) != j-i {
+			return &NodeError{Command: "getBalances", Message: "node returned a wrong number of balances"}
+		}
+
+		mu.Lock()
+		copy(resp.Balances[i:j], r.Balances)
+		if r.Duration > resp.Duration {
+			resp.Duration = r.Duration
+		}
+		if resp.Milestone == "" || r.MilestoneIndex < resp.MilestoneIndex {
+			resp.Milestone = r.Milestone
+			resp.MilestoneIndex = r.MilestoneIndex
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
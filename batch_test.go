@@ -0,0 +1,173 @@
+package giota
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// batchTestNode answers findTransactions with one hash per address plus a hash
+// shared by all requests, and getBalances with the index of the address.
+type batchTestNode struct {
+	mu       sync.Mutex
+	requests int
+	maxSize  int
+}
+
+func (n *batchTestNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Command   string    `json:"command"`
+		Addresses []Address `json:"addresses"`
+		Hashes    []Trytes  `json:"hashes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.requests++
+	if l := len(req.Addresses) + len(req.Hashes); l > n.maxSize {
+		n.maxSize = l
+	}
+	milestone := int64(100 - n.requests)
+	n.mu.Unlock()
+
+	switch req.Command {
+	case "findTransactions":
+		hashes := []Trytes{EmptyHash}
+		for _, a := range req.Addresses {
+			hashes = append(hashes, Trytes(a))
+		}
+		json.NewEncoder(w).Encode(&FindTransactionsResponse{Hashes: hashes})
+	case "getBalances":
+		bals := make([]string, len(req.Addresses))
+		for i, a := range req.Addresses {
+			bals[i] = string(a[:3])
+		}
+		json.NewEncoder(w).Encode(&GetBalancesResponse{Balances: bals, MilestoneIndex: milestone, Milestone: EmptyHash})
+	case "getTrytes":
+		txs := make([]Transaction, len(req.Hashes))
+		for i, h := range req.Hashes {
+			txs[i] = Transaction{Address: Address(h)}
+		}
+		json.NewEncoder(w).Encode(&GetTrytesResponse{Trytes: txs})
+	}
+}
+
+func batchTestAddresses(n int) []Address {
+	adrs := make([]Address, n)
+	for i := range adrs {
+		adrs[i] = Address(Int2Trits(int64(i+1), 9).Trytes() + Trytes(strings.Repeat("9", 78)))
+	}
+	return adrs
+}
+
+func TestBatching(t *testing.T) {
+	node := &batchTestNode{}
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+
+	api := NewAPI(ts.URL, nil)
+	api.SetBatching(10, 3)
+	adrs := batchTestAddresses(25)
+
+	ft, err := api.FindTransactions(&FindTransactionsRequest{Addresses: adrs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ft.Hashes) != 26 {
+		t.Errorf("FindTransactions() returned %d hashes, expected 26 without duplicates", len(ft.Hashes))
+	}
+	if node.requests != 3 || node.maxSize != 10 {
+		t.Errorf("FindTransactions() sent %d requests of up to %d addresses", node.requests, node.maxSize)
+	}
+
+	hashes := make([]Trytes, len(adrs))
+	for i, a := range adrs {
+		hashes[i] = Trytes(a)
+	}
+	gt, err := api.GetTrytes(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tx := range gt.Trytes {
+		if tx.Address != adrs[i] {
+			t.Errorf("GetTrytes() returned transaction %d out of order", i)
+		}
+	}
+
+	node.requests = 0
+	gb, err := api.GetBalances(adrs, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range gb.Balances {
+		if b != string(adrs[i][:3]) {
+			t.Errorf("GetBalances() returned balance %d out of order", i)
+		}
+	}
+	if gb.MilestoneIndex != 97 {
+		t.Errorf("GetBalances() returned milestone %d, expected the oldest one", gb.MilestoneIndex)
+	}
+
+	// requests below the batch size are sent as is
+	node.requests = 0
+	if _, err := api.FindTransactions(&FindTransactionsRequest{Addresses: adrs[:5]}); err != nil {
+		t.Fatal(err)
+	}
+	if node.requests != 1 {
+		t.Errorf("FindTransactions() sent %d requests for a small request", node.requests)
+	}
+}
+
+func TestBatchingError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"too many addresses"}`))
+	}))
+	defer ts.Close()
+
+	api := NewAPI(ts.URL, nil)
+	api.SetBatching(10, 2)
+	if _, err := api.FindTransactions(&FindTransactionsRequest{Addresses: batchTestAddresses(50)}); err == nil {
+		t.Error("FindTransactions() expected an error")
+	}
+}
+
+// noTrytesAPI fails getTrytes requests.
+type noTrytesAPI struct {
+	*scanMockAPI
+}
+
+func (noTrytesAPI) GetTrytes([]Trytes) (*GetTrytesResponse, error) {
+	return nil, errors.New("trytes were fetched")
+}
+
+func TestUsedAddresses(t *testing.T) {
+	adrs := batchTestAddresses(usedAddressWindow)
+	m := &scanMockAPI{txs: make(map[Trytes]Transaction)}
+	for _, i := range []int{0, 1, 2, 7} {
+		m.txs[Trytes(adrs[i])] = Transaction{Address: adrs[i]}
+	}
+
+	used, err := usedAddresses(noTrytesAPI{m}, adrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range adrs {
+		want := i <= 2 || i == 7
+		if used[a] != want {
+			t.Errorf("address %d used is %t, want %t", i, used[a], want)
+		}
+	}
+
+	used, err = usedAddresses(noTrytesAPI{&scanMockAPI{}}, adrs)
+	if err != nil || len(used) != 0 {
+		t.Errorf("usedAddresses() of unused addresses = %v, %v", used, err)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/decred/base58"
 )
 
+// ErrInvalidCommitment is returned when commitment trytes do not encode a point.
+var ErrInvalidCommitment = errors.New("commitment is not a valid point")
+
 type PreProof struct {
 	commitment *Commitment
 	receiver *Address
@@ -56,7 +59,13 @@ func (c *Commitment) Decode() (ECPoint, error) {
 		return ECPoint{}, err
 	}
 	byteKey := base58.Decode(asciKey)
+	if len(byteKey) < 33 {
+		return ECPoint{}, ErrInvalidCommitment
+	}
 	pkKey, err := secp256k1.ParsePubKey(byteKey[:33])
+	if err != nil {
+		return ECPoint{}, err
+	}
 
 	return ECPoint{pkKey.GetX(), pkKey.GetY()}, nil
 }
@@ -102,6 +102,12 @@ func (bs *Bundle) Add(num int, address Address, value *Commitment, timestamp tim
 	return nil
 }
 
+// isContinuation returns true if the transaction at i carries the rest of the
+// message of the output before it, so it has no commitment of its own.
+func (bs Bundle) isContinuation(i int) bool {
+	return i > 0 && bs[i].Address == bs[i-1].Address && isEmptyTrytes(bs[i].VectorP)
+}
+
 // Finalize filled sigs, bundlehash, and indices elements in bundle.
 func (bs Bundle) Finalize(sig []Trytes) {
 	h := bs.getValidHash()
@@ -117,34 +123,27 @@ func (bs Bundle) Finalize(sig []Trytes) {
 	}
 }
 
-// Hash calculates hash of Bundle.
+// Hash calculates hash of Bundle in the current hash scheme.
 func (bs Bundle) Hash() Trytes {
-	k := NewKerl()
+	h := CurrentHashScheme().Bundle()
 	buf := make(Trits, 243+243*3)
 
 	for i, b := range bs {
 		getTritsToHash(buf, &b, i, len(bs))
-		k.Absorb(buf)
+		h.Write(buf)
 	}
 
-	h, _ := k.Squeeze(HashSize)
-	return h.Trytes()
+	return h.Sum(nil).Trytes()
 }
 
 // getValidHash calculates hash of Bundle and increases ObsoleteTag value
 // until normalized hash doesn't have any 13
 func (bs Bundle) getValidHash() Trytes {
-	k := NewKerl()
-	hashedLen := 486
-	buf := make(Trits, hashedLen*len(bs))
-	for i, b := range bs {
-		getTritsToHash(buf[i*hashedLen:], &b, i, len(bs))
-	}
+	newHasher := CurrentHashScheme().Bundle
+	buf := bs.essence()
 	var i = 0
 	for {
-		k.Absorb(buf)
-		hashTrits, _ := k.Squeeze(HashSize)
-		h := hashTrits.Trytes()
+		h := hashTrits(newHasher, buf)
 		n := h.Normalize()
 		valid := true
 
@@ -161,12 +160,29 @@ func (bs Bundle) getValidHash() Trytes {
 			return h
 		}
 		i++
-		k.Reset()
 		incTrits(buf[offset : offset+ObsoleteTagTrinarySize])
 
 	}
 }
 
+// essenceSize is the number of trits of a transaction hashed by getValidHash.
+const essenceSize = 486
+
+// essence returns the trits of bs hashed by getValidHash.
+func (bs Bundle) essence() Trits {
+	buf := make(Trits, essenceSize*len(bs))
+	for i, b := range bs {
+		getTritsToHash(buf[i*essenceSize:], &b, i, len(bs))
+	}
+	return buf
+}
+
+// finalizedHash returns the bundle hash written by Finalize, which differs
+// from Hash.
+func (bs Bundle) finalizedHash() Trytes {
+	return hashTrits(CurrentHashScheme().Bundle, bs.essence())
+}
+
 func getTritsToHash(buf Trits, b *Transaction, i, l int) {
 	copy(buf, Trytes(b.Address).Trits())
 	copy(buf[243:], Int2Trits(0, 81))
@@ -209,6 +225,9 @@ func (bs Bundle) IsValid() error {
 	ecPoints := make([]bp_go.ECPoint, len(bs))
 
 	for i, b := range bs {
+		if bs.isContinuation(i) {
+			continue
+		}
 		commitments[i].Trytes = Trytes(b.VectorP)
 		ecPoint, err :=commitments[i].Decode()
 
@@ -233,6 +252,8 @@ func (bs Bundle) IsValid() error {
 			return fmt.Errorf("CurrentIndex of index %d is not correct", b.CurrentIndex)
 		case b.LastIndex != int64(len(bs)-1):
 			return fmt.Errorf("LastIndex of index %d is not correct", b.CurrentIndex)
+		case bs.isContinuation(index):
+			continue
 		case b.RangeProof[0:6] == "999999":
 			continue
 		}
@@ -0,0 +1,189 @@
+package giota
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PowProgress reports the nonce found for a transaction of a bundle.
+type PowProgress struct {
+	PowResult
+
+	// Index is the index of the transaction in the bundle and Hash its hash
+	// with the nonce.
+	Index int
+	Hash  Trytes
+
+	// Done is the number of transactions with a nonce, including those
+	// attached before a resume, and Total the number in the bundle.
+	Done  int
+	Total int
+}
+
+// BundlePow does the PoW of the transactions of a bundle and chains them to
+// a trunk and a branch transaction like attachToTangle does.
+//
+// The transactions already attached are kept when Run fails or is cancelled,
+// so calling it again resumes the PoW. A bundle attached partially by another
+// BundlePow is resumed too if it is chained to the same trunk and branch.
+type BundlePow struct {
+	// Progress is called after every nonce found if it is not nil.
+	Progress func(PowProgress)
+
+	engine        *PowEngine
+	trunk, branch Trytes
+	mwm           int
+	txs           []Transaction
+
+	// layouts are the layouts of the versions of txs, nil if unknown.
+	layouts []*Layout
+
+	// mids are the hashers after writing the trits of each transaction
+	// which are the same however it is chained, computed once.
+	mids []TritHasher
+}
+
+// NewBundlePow returns a BundlePow searching the nonces of txs with e, the
+// transactions being hashed like e hashes them. txs is left unmodified.
+func NewBundlePow(e *PowEngine, txs []Transaction, trunk, branch Trytes, mwm int) *BundlePow {
+	b := &BundlePow{
+		engine:  e,
+		trunk:   trunk,
+		branch:  branch,
+		mwm:     mwm,
+		txs:     make([]Transaction, len(txs)),
+		mids:    make([]TritHasher, len(txs)),
+		layouts: make([]*Layout, len(txs)),
+	}
+	copy(b.txs, txs)
+	for i := range txs {
+		b.layouts[i], _ = LayoutOf(txs[i].Version)
+	}
+	return b
+}
+
+// Transactions returns the transactions of the bundle, attached or not.
+func (b *BundlePow) Transactions() []Transaction {
+	txs := make([]Transaction, len(b.txs))
+	copy(txs, b.txs)
+	return txs
+}
+
+// Attached returns the number of transactions at the end of the bundle which
+// are chained and have a valid nonce.
+func (b *BundlePow) Attached() int {
+	n, _ := b.attached()
+	return n
+}
+
+// attached returns Attached() and the hash of the first transaction attached.
+func (b *BundlePow) attached() (int, Trytes) {
+	var prev Trytes
+	for i := len(b.txs) - 1; i >= 0; i-- {
+		trunk, branch := b.chain(i, prev)
+		if b.layouts[i] == nil || b.txs[i].TrunkTransaction != trunk || b.txs[i].BranchTransaction != branch {
+			return len(b.txs) - 1 - i, prev
+		}
+
+		h := b.hash(i)
+		if h.Trits().TrailingZeros() < int64(b.mwm) {
+			return len(b.txs) - 1 - i, prev
+		}
+		prev = h
+	}
+	return len(b.txs), prev
+}
+
+// chain returns the trunk and branch of the ith transaction when the hash of
+// the next one is prev.
+func (b *BundlePow) chain(i int, prev Trytes) (Trytes, Trytes) {
+	if i == len(b.txs)-1 {
+		return b.trunk, b.branch
+	}
+	return prev, b.trunk
+}
+
+// trits returns the trits of the ith transaction and the offset of the first
+// chunk absorbed by Curl which changes when it is chained and attached. The
+// layout of the transaction must be known.
+func (b *BundlePow) trits(i int) (Trits, int) {
+	l := b.layouts[i]
+	return l.Trits(&b.txs[i]), l.chainOffset()
+}
+
+// mid returns a hasher which absorbed the trits of the ith transaction
+// before its chain offset.
+func (b *BundlePow) mid(i int) TritHasher {
+	if b.mids[i] == nil {
+		tr, off := b.trits(i)
+		h := b.engine.hasher()
+		h.Write(tr[:off])
+		b.mids[i] = h
+	}
+	return b.mids[i].Clone()
+}
+
+func (b *BundlePow) hash(i int) Trytes {
+	tr, off := b.trits(i)
+	h := b.mid(i)
+	h.Write(tr[off:])
+	return h.Sum(nil).Trytes()
+}
+
+// search searches the nonce of the ith transaction, from the hasher which
+// absorbed its trits before the chain offset unless e has its own search.
+func (b *BundlePow) search(ctx context.Context, i int) (PowResult, error) {
+	tr, off := b.trits(i)
+	if b.engine.search != nil {
+		return b.engine.searchFunc(ctx, tr.Trytes(), b.mwm)
+	}
+	j, err := newPowJobFrom(b.mid(i), tr[off:], b.mwm)
+	if err != nil {
+		return PowResult{}, err
+	}
+	return b.engine.run(ctx, j)
+}
+
+// Run searches the nonces of the transactions not attached yet, from the last
+// one to the first. It returns the error of ctx if ctx is done first, and an
+// error without searching if the layout of a transaction is unknown.
+func (b *BundlePow) Run(ctx context.Context) error {
+	for i, l := range b.layouts {
+		if l == nil {
+			return fmt.Errorf("transaction %d: %s", i, ErrUnknownLayout)
+		}
+	}
+
+	done, prev := b.attached()
+	for i := len(b.txs) - 1 - done; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tx := &b.txs[i]
+		tx.TrunkTransaction, tx.BranchTransaction = b.chain(i, prev)
+		tx.AttachmentTimestamp = Int2Trits(time.Now().UnixNano()/1000000, TimestampTrinarySize).Trytes()
+		tx.AttachmentTimestampLowerBound = ""
+		tx.AttachmentTimestampUpperBound = maxTimestampTrytes
+
+		r, err := b.search(ctx, i)
+		if err != nil {
+			return err
+		}
+
+		tx.Nonce = r.Nonce
+		prev = b.hash(i)
+		done++
+		if b.Progress != nil {
+			b.Progress(PowProgress{
+				PowResult: r,
+				Index:     i,
+				Hash:      prev,
+				Done:      done,
+				Total:     len(b.txs),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package giota
+
+import (
+	"context"
+	"testing"
+)
+
+func testPowBundle(n int) []Transaction {
+	txs := make([]Transaction, n)
+	for i := range txs {
+		txs[i] = testPowTransaction()
+		txs[i].CurrentIndex = int64(i)
+		txs[i].LastIndex = int64(n - 1)
+	}
+	return txs
+}
+
+func checkPowBundle(t *testing.T, txs []Transaction, trunk, branch Trytes, mwm int64) {
+	for i := len(txs) - 1; i >= 0; i-- {
+		tx := txs[i]
+		if !tx.HasValidNonce(mwm) {
+			t.Errorf("transaction %d has an invalid nonce", i)
+		}
+
+		wantTrunk, wantBranch := trunk, branch
+		if i < len(txs)-1 {
+			wantTrunk, wantBranch = txs[i+1].Hash(), trunk
+		}
+		if tx.TrunkTransaction != wantTrunk || tx.BranchTransaction != wantBranch {
+			t.Errorf("transaction %d is not chained", i)
+		}
+	}
+}
+
+func TestBundlePow(t *testing.T) {
+	trunk, branch := EmptyHash, Trytes("A")+EmptyHash[1:]
+	txs := testPowBundle(3)
+	b := NewBundlePow(BestPowEngine(), txs, trunk, branch, 9)
+
+	var progress []PowProgress
+	b.Progress = func(p PowProgress) {
+		progress = append(progress, p)
+	}
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	attached := b.Transactions()
+	checkPowBundle(t, attached, trunk, branch, 9)
+	if b.Attached() != 3 {
+		t.Errorf("Attached() = %d, want 3", b.Attached())
+	}
+	if txs[0].Nonce != "" {
+		t.Error("the transactions given were modified")
+	}
+
+	if len(progress) != 3 {
+		t.Fatalf("progress was reported %d times, want 3", len(progress))
+	}
+	for n, p := range progress {
+		if p.Index != 2-n || p.Done != n+1 || p.Total != 3 || p.Hashes <= 0 {
+			t.Errorf("progress %d is wrong: %+v", n, p)
+		}
+		if p.Hash != attached[p.Index].Hash() {
+			t.Errorf("progress %d has a wrong hash", n)
+		}
+	}
+}
+
+func TestBundlePowResume(t *testing.T) {
+	trunk, branch := EmptyHash, EmptyHash
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBundlePow(BestPowEngine(), testPowBundle(3), trunk, branch, 9)
+	b.Progress = func(PowProgress) { cancel() }
+	if err := b.Run(ctx); err != context.Canceled {
+		t.Fatalf("Run() returned %v after its context was cancelled", err)
+	}
+	if b.Attached() != 1 {
+		t.Fatalf("Attached() = %d after an interrupted run, want 1", b.Attached())
+	}
+
+	// a new BundlePow resumes the transactions attached partially
+	b = NewBundlePow(BestPowEngine(), b.Transactions(), trunk, branch, 9)
+	if b.Attached() != 1 {
+		t.Fatalf("Attached() = %d of the resumed bundle, want 1", b.Attached())
+	}
+	var done []int
+	b.Progress = func(p PowProgress) { done = append(done, p.Done) }
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 || done[0] != 2 || done[1] != 3 {
+		t.Errorf("resumed run reported %v transactions done", done)
+	}
+	checkPowBundle(t, b.Transactions(), trunk, branch, 9)
+}
+
+func TestBundlePowUnknownLayout(t *testing.T) {
+	txs := testPowBundle(2)
+	txs[0].Version = 99
+	b := NewBundlePow(BestPowEngine(), txs, EmptyHash, EmptyHash, 1)
+	if err := b.Run(context.Background()); err == nil {
+		t.Error("Run() of a transaction of an unknown layout succeeded")
+	}
+	if b.Attached() != 0 || b.Transactions()[1].Nonce != "" {
+		t.Error("transactions were attached")
+	}
+}
+
+func TestBundlePowEngineFunc(t *testing.T) {
+	var searched []Trytes
+	e := NewPowEngineFunc("test", func(ctx context.Context, trytes Trytes, mwm int) (Trytes, error) {
+		searched = append(searched, trytes)
+		r, err := BestPowEngine().Search(ctx, trytes, mwm)
+		return r.Nonce, err
+	})
+	if _, err := e.WithRounds(CurlP27); err != ErrPowRounds {
+		t.Errorf("WithRounds() of an engine of a func returned %v", err)
+	}
+
+	txs := testPowBundle(2)
+	b := NewBundlePow(e, txs, EmptyHash, EmptyHash, 9)
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkPowBundle(t, b.Transactions(), EmptyHash, EmptyHash, 9)
+	if len(searched) != 2 || len(searched[0]) != len(txs[0].Trytes()) {
+		t.Errorf("the func searched %d transactions", len(searched))
+	}
+}
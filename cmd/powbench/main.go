@@ -0,0 +1,79 @@
+// Command powbench measures the speed of the PoW implementations built into
+// giota on this machine. It prints the hash rate of each one and the times to
+// find a nonce at several MWMs, from the fastest implementation to the
+// slowest, and can save the fastest one for giota.UseFastestPoW.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+func main() {
+	var (
+		duration = flag.Duration("duration", 10*time.Second, "time to benchmark each implementation")
+		mwms     = flag.String("mwm", joinInts(giota.DefaultPowBenchmarkMWMs), "comma separated MWMs to search nonces with")
+		cache    = flag.String("cache", "", "file to save the fastest implementation in, for giota.UseFastestPoW")
+	)
+	flag.Parse()
+
+	ms, err := parseInts(*mwms)
+	if err != nil {
+		log.Fatalf("invalid -mwm: %v", err)
+	}
+
+	bs := giota.BenchmarkPoW(*duration, ms...)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "implementation\tMWM\tkH/s\tnonces\tp50\tp90\tmax\t")
+	for _, b := range bs {
+		fmt.Fprintf(w, "%s\t\t%.0f\t\t\t\t\t\n", b.Name, b.HashRate()/1000)
+		for _, m := range b.MWMs {
+			fmt.Fprintf(w, "\t%d\t%.0f\t%d\t%s\t%s\t%s\t\n", m.MWM, m.HashRate()/1000, len(m.Nonces),
+				nonceTime(m.Percentile(0.5)), nonceTime(m.Percentile(0.9)), nonceTime(m.Percentile(1)))
+		}
+	}
+	w.Flush()
+
+	if *cache != "" {
+		if err := giota.WritePowBenchmark(*cache, bs); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// nonceTime formats a time to find a nonce, which is zero if none was found.
+func nonceTime(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
+
+func joinInts(is []int) string {
+	ss := make([]string, len(is))
+	for i, n := range is {
+		ss[i] = strconv.Itoa(n)
+	}
+	return strings.Join(ss, ",")
+}
+
+func parseInts(s string) ([]int, error) {
+	var is []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		is = append(is, n)
+	}
+	return is, nil
+}
@@ -0,0 +1,41 @@
+// Command powsrv does the PoW of attachToTangle for light clients which can't
+// do it themselves. It serves the attachToTangle and interruptAttachingToTangle
+// commands of the IRI HTTP API with the best PoW available to giota, queues
+// the requests and limits how many each client can have in flight.
+// Metrics are served in the Prometheus text format at /metrics.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/peterdouglas/giota"
+)
+
+func main() {
+	var (
+		listen    = flag.String("listen", "localhost:14265", "address to listen on")
+		maxMWM    = flag.Int64("max-mwm", giota.DefaultMinWeightMagnitude, "highest minimum weight magnitude accepted")
+		queueSize = flag.Int("queue", 16, "number of requests waiting for PoW before new ones are rejected")
+		perClient = flag.Int("per-client", 1, "number of requests a client can have queued or running")
+		workers   = flag.Int("workers", 1, "number of requests doing PoW at the same time, sharing the CPUs")
+	)
+	flag.Parse()
+
+	e := giota.BestPowEngine()
+	s := newService(config{
+		engine:    e,
+		maxMWM:    *maxMWM,
+		workers:   *workers,
+		queueSize: *queueSize,
+		perClient: *perClient,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics)
+	mux.Handle("/", s)
+
+	log.Printf("listening on %s, doing PoW with %s", *listen, e.Name())
+	log.Fatal(http.ListenAndServe(*listen, mux))
+}
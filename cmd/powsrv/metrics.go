@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// results of jobs
+const (
+	resultOK          = "ok"
+	resultError       = "error"
+	resultInterrupted = "interrupted"
+	resultRejected    = "rejected"
+)
+
+// metrics of the service, exposed in the Prometheus text format.
+type metrics struct {
+	mu           sync.Mutex
+	waiting      int64
+	running      int64
+	jobs         map[string]int64
+	transactions int64
+	powSeconds   float64
+}
+
+func newMetrics() *metrics {
+	m := &metrics{jobs: make(map[string]int64)}
+	for _, r := range []string{resultOK, resultError, resultInterrupted, resultRejected} {
+		m.jobs[r] = 0
+	}
+	return m
+}
+
+func (m *metrics) queued() {
+	m.mu.Lock()
+	m.waiting++
+	m.mu.Unlock()
+}
+
+func (m *metrics) started() {
+	m.mu.Lock()
+	m.waiting--
+	m.running++
+	m.mu.Unlock()
+}
+
+func (m *metrics) rejected() {
+	m.mu.Lock()
+	m.jobs[resultRejected]++
+	m.mu.Unlock()
+}
+
+// cancelled records a job interrupted while it was queued.
+func (m *metrics) cancelled() {
+	m.mu.Lock()
+	m.waiting--
+	m.jobs[resultInterrupted]++
+	m.mu.Unlock()
+}
+
+// finished records a job which was started.
+func (m *metrics) finished(result string, txs int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs[result]++
+	m.running--
+	if result == resultOK {
+		m.transactions += int64(txs)
+	}
+	m.powSeconds += d.Seconds()
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP powsrv_jobs_total attachToTangle requests by result.")
+	fmt.Fprintln(w, "# TYPE powsrv_jobs_total counter")
+	results := make([]string, 0, len(m.jobs))
+	for r := range m.jobs {
+		results = append(results, r)
+	}
+	sort.Strings(results)
+	for _, r := range results {
+		fmt.Fprintf(w, "powsrv_jobs_total{result=%q} %d\n", r, m.jobs[r])
+	}
+
+	fmt.Fprintln(w, "# HELP powsrv_jobs_queued Jobs waiting for a worker.")
+	fmt.Fprintln(w, "# TYPE powsrv_jobs_queued gauge")
+	fmt.Fprintf(w, "powsrv_jobs_queued %d\n", m.waiting)
+
+	fmt.Fprintln(w, "# HELP powsrv_jobs_running Jobs doing PoW.")
+	fmt.Fprintln(w, "# TYPE powsrv_jobs_running gauge")
+	fmt.Fprintf(w, "powsrv_jobs_running %d\n", m.running)
+
+	fmt.Fprintln(w, "# HELP powsrv_transactions_total Transactions attached.")
+	fmt.Fprintln(w, "# TYPE powsrv_transactions_total counter")
+	fmt.Fprintf(w, "powsrv_transactions_total %d\n", m.transactions)
+
+	fmt.Fprintln(w, "# HELP powsrv_pow_seconds_total Time spent doing PoW.")
+	fmt.Fprintln(w, "# TYPE powsrv_pow_seconds_total counter")
+	fmt.Fprintf(w, "powsrv_pow_seconds_total %g\n", m.powSeconds)
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.write(w)
+}
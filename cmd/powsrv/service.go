@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+var errInterrupted = errors.New("attaching to tangle was interrupted")
+
+type config struct {
+	// engine searches the nonces of the bundles.
+	engine *giota.PowEngine
+
+	// maxMWM is the highest minimum weight magnitude accepted.
+	maxMWM int64
+
+	// workers is the number of jobs doing PoW at the same time.
+	workers int
+
+	// queueSize is the number of jobs waiting for a worker.
+	queueSize int
+
+	// perClient is the number of jobs a client can have queued or running.
+	perClient int
+}
+
+// job is an attachToTangle request being served.
+type job struct {
+	client    string
+	interrupt chan struct{}
+	once      sync.Once
+}
+
+func (j *job) stop() {
+	j.once.Do(func() { close(j.interrupt) })
+}
+
+// service does the PoW of attachToTangle requests in a bounded queue.
+type service struct {
+	cfg     config
+	metrics *metrics
+
+	// slots holds a token for every queued or running job and workers one
+	// for every running job.
+	slots   chan struct{}
+	workers chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]map[*job]struct{}
+}
+
+func newService(cfg config) *service {
+	return &service{
+		cfg:     cfg,
+		metrics: newMetrics(),
+		slots:   make(chan struct{}, cfg.workers+cfg.queueSize),
+		workers: make(chan struct{}, cfg.workers),
+		jobs:    make(map[string]map[*job]struct{}),
+	}
+}
+
+// ServeHTTP serves the commands of the client at the remote address of r.
+func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	giota.NewServer(&client{s: s, addr: host}).ServeHTTP(w, r)
+}
+
+// client is the NodeService of a single client.
+type client struct {
+	giota.UnimplementedNodeService
+
+	s    *service
+	addr string
+}
+
+func (c *client) AttachToTangle(req *giota.AttachToTangleRequest) (*giota.AttachToTangleResponse, error) {
+	return c.s.attach(c.addr, req)
+}
+
+// InterruptAttachingToTangle stops the jobs of the client only.
+func (c *client) InterruptAttachingToTangle(*giota.InterruptAttachingToTangleRequest) error {
+	c.s.interrupt(c.addr)
+	return nil
+}
+
+func (s *service) add(addr string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.jobs[addr]) >= s.cfg.perClient {
+		return nil, &giota.NodeError{
+			Command:    "attachToTangle",
+			StatusCode: http.StatusTooManyRequests,
+			Message:    fmt.Sprintf("too many concurrent requests, at most %d are allowed", s.cfg.perClient),
+		}
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil, &giota.NodeError{
+			Command:    "attachToTangle",
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "the job queue is full",
+		}
+	}
+
+	j := &job{client: addr, interrupt: make(chan struct{})}
+	if s.jobs[addr] == nil {
+		s.jobs[addr] = make(map[*job]struct{})
+	}
+	s.jobs[addr][j] = struct{}{}
+	return j, nil
+}
+
+func (s *service) remove(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs[j.client], j)
+	if len(s.jobs[j.client]) == 0 {
+		delete(s.jobs, j.client)
+	}
+	<-s.slots
+}
+
+func (s *service) interrupt(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for j := range s.jobs[addr] {
+		j.stop()
+	}
+}
+
+func (s *service) attach(addr string, req *giota.AttachToTangleRequest) (*giota.AttachToTangleResponse, error) {
+	if req.MinWeightMagnitude < 1 || req.MinWeightMagnitude > s.cfg.maxMWM {
+		s.metrics.rejected()
+		return nil, fmt.Errorf("MWM: %d is invalid, it must be between 1 and %d", req.MinWeightMagnitude, s.cfg.maxMWM)
+	}
+	if len(req.Trytes) == 0 {
+		s.metrics.rejected()
+		return nil, errors.New("no transactions to attach")
+	}
+
+	j, err := s.add(addr)
+	if err != nil {
+		s.metrics.rejected()
+		return nil, err
+	}
+	defer s.remove(j)
+
+	s.metrics.queued()
+	select {
+	case s.workers <- struct{}{}:
+	case <-j.interrupt:
+		s.metrics.cancelled()
+		return nil, errInterrupted
+	}
+	defer func() { <-s.workers }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-j.interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	s.metrics.started()
+	start := time.Now()
+	resp, err := giota.LocalAttachToTangleContext(ctx, req, s.cfg.engine, nil)
+	if err != nil && ctx.Err() != nil {
+		err = errInterrupted
+	}
+
+	result := resultOK
+	switch {
+	case err == errInterrupted:
+		result = resultInterrupted
+	case err != nil:
+		result = resultError
+	}
+	s.metrics.finished(result, len(req.Trytes), time.Since(start))
+	return resp, err
+}
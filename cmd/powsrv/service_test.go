@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+var nonce = giota.Trytes(strings.Repeat("9", giota.NonceTrinarySize/3))
+
+func testRequest(n int) *giota.AttachToTangleRequest {
+	txs := make([]giota.Transaction, n)
+	for i := range txs {
+		txs[i] = giota.Transaction{
+			Address:      giota.Address(strings.Repeat("A", 81)),
+			Timestamp:    time.Unix(1500000000, 0),
+			CurrentIndex: int64(i),
+			LastIndex:    int64(n - 1),
+			Bundle:       giota.EmptyHash,
+		}
+	}
+	return &giota.AttachToTangleRequest{
+		TrunkTransaction:   giota.EmptyHash,
+		BranchTransaction:  giota.EmptyHash,
+		MinWeightMagnitude: 9,
+		Trytes:             txs,
+	}
+}
+
+var constantPow = giota.NewPowEngineFunc("constant", func(context.Context, giota.Trytes, int) (giota.Trytes, error) {
+	return nonce, nil
+})
+
+// blockingPow returns an engine whose searches signal started and wait for the
+// end of their context.
+func blockingPow(started chan<- struct{}) *giota.PowEngine {
+	return giota.NewPowEngineFunc("blocking", func(ctx context.Context, _ giota.Trytes, _ int) (giota.Trytes, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+}
+
+func statusOf(err error) int {
+	if ne, ok := err.(*giota.NodeError); ok {
+		return ne.StatusCode
+	}
+	return 0
+}
+
+func TestAttach(t *testing.T) {
+	s := newService(config{
+		engine:    constantPow,
+		maxMWM:    14,
+		workers:   1,
+		queueSize: 1,
+		perClient: 1,
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	api := giota.NewAPI(srv.URL, srv.Client())
+	api.SetRetryPolicy(giota.NoRetry)
+
+	resp, err := api.AttachToTangle(testRequest(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Trytes) != 3 || resp.Trytes[2].TrunkTransaction != giota.EmptyHash ||
+		resp.Trytes[1].TrunkTransaction != resp.Trytes[2].Hash() {
+		t.Error("transactions were not attached")
+	}
+
+	req := testRequest(1)
+	req.MinWeightMagnitude = 15
+	if _, err = api.AttachToTangle(req); err == nil {
+		t.Error("a MWM above the maximum was accepted")
+	}
+
+	if _, err = api.GetNodeInfo(); err == nil {
+		t.Error("getNodeInfo is served")
+	}
+
+	var b bytes.Buffer
+	s.metrics.write(&b)
+	for _, m := range []string{
+		`powsrv_jobs_total{result="ok"} 1`,
+		`powsrv_jobs_total{result="rejected"} 1`,
+		"powsrv_transactions_total 3",
+		"powsrv_jobs_running 0",
+	} {
+		if !strings.Contains(b.String(), m) {
+			t.Errorf("metrics do not contain %s:\n%s", m, b.String())
+		}
+	}
+}
+
+func TestAttachLimits(t *testing.T) {
+	started := make(chan struct{})
+	s := newService(config{
+		engine:    blockingPow(started),
+		maxMWM:    14,
+		workers:   1,
+		queueSize: 1,
+		perClient: 1,
+	})
+
+	errs := make(chan error, 2)
+	attach := func(addr string) {
+		_, err := s.attach(addr, testRequest(2))
+		errs <- err
+	}
+
+	go attach("a")
+	<-started
+
+	if _, err := s.attach("a", testRequest(1)); statusOf(err) != http.StatusTooManyRequests {
+		t.Errorf("second request of a client got %v", err)
+	}
+
+	// b waits in the queue, c doesn't fit in it
+	go attach("b")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.slots) != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.attach("c", testRequest(1)); statusOf(err) != http.StatusServiceUnavailable {
+		t.Errorf("request to a full queue got %v", err)
+	}
+
+	// the queued job of b is cancelled, the search of a is stopped
+	s.interrupt("b")
+	if err := <-errs; err != errInterrupted {
+		t.Errorf("queued job was not interrupted: %v", err)
+	}
+	s.interrupt("a")
+	if err := <-errs; err != errInterrupted {
+		t.Errorf("running job was not interrupted: %v", err)
+	}
+
+	if len(s.slots) != 0 || len(s.jobs) != 0 {
+		t.Error("jobs were not removed")
+	}
+}
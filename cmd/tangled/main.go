@@ -0,0 +1,57 @@
+// Command tangled runs a local tangle simulator exposing the IRI HTTP API.
+// A coordinator issues milestones at a fixed interval so that transactions sent
+// to it get confirmed like on a real network.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/peterdouglas/giota"
+	"github.com/peterdouglas/giota/fakenode"
+	"github.com/peterdouglas/giota/tangle"
+)
+
+func main() {
+	var (
+		listen   = flag.String("listen", "localhost:14265", "address to listen on")
+		interval = flag.Duration("milestone-interval", time.Minute, "interval between milestones")
+		depth    = flag.Int64("depth", giota.Depth, "milestones below the latest one where tip selection starts")
+		alpha    = flag.Float64("alpha", tangle.DefaultAlpha, "bias of tip selection towards heavy subtangles, 0 for uniform")
+		mwm      = flag.Int64("mwm", giota.DefaultMinWeightMagnitude, "minimum weight magnitude of stored transactions")
+		feed     = flag.String("feed", "", "address to publish the transaction feed on, disabled if empty")
+	)
+	flag.Parse()
+
+	cfg := tangle.Config{
+		Depth:             *depth,
+		Alpha:             *alpha,
+		UniformWalk:       *alpha == 0,
+		MilestoneInterval: *interval,
+	}
+
+	if *feed != "" {
+		f, err := giota.NewFeedServer(*feed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		cfg.OnTransaction = f.PublishTransaction
+		cfg.OnConfirmation = f.PublishConfirmation
+		log.Printf("publishing the transaction feed on %s", f.Addr())
+	}
+
+	t := tangle.New(cfg)
+	t.Start()
+	defer t.Stop()
+
+	h := fakenode.NewHandler(t)
+	h.MinWeightMagnitude = *mwm
+	h.AppName = "tangled"
+
+	log.Printf("listening on %s, issuing milestones every %s", *listen, *interval)
+	log.Fatal(http.ListenAndServe(*listen, h))
+}
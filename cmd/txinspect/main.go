@@ -0,0 +1,113 @@
+// Command txinspect prints the decoded content of transactions. It reads the
+// trytes of transactions separated by white space, or a JSON array of them,
+// from the files given as arguments or from the standard input.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/peterdouglas/giota"
+)
+
+func main() {
+	var (
+		asJSON   = flag.Bool("json", false, "print JSON instead of text")
+		asBundle = flag.Bool("bundle", false, "describe the transactions as a bundle")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var txs []giota.Transaction
+	if flag.NArg() == 0 {
+		txs = readOrExit(os.Stdin, "stdin")
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		txs = append(txs, readOrExit(f, name)...)
+		f.Close()
+	}
+
+	var v interface{}
+	switch {
+	case *asBundle:
+		v = giota.Bundle(txs).Describe()
+	default:
+		ds := make([]*giota.TransactionDescription, len(txs))
+		for i := range txs {
+			ds[i] = txs[i].Describe()
+		}
+		v = ds
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	switch d := v.(type) {
+	case *giota.BundleDescription:
+		fmt.Print(d)
+	case []*giota.TransactionDescription:
+		for i, t := range d {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(t)
+		}
+	}
+}
+
+func readOrExit(r io.Reader, name string) []giota.Transaction {
+	txs, err := read(r)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return txs
+}
+
+// read reads transactions separated by white space or in a JSON array.
+func read(r io.Reader) ([]giota.Transaction, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var trytes []giota.Trytes
+	s := strings.TrimSpace(string(b))
+	if strings.HasPrefix(s, "[") {
+		if err = json.Unmarshal([]byte(s), &trytes); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, f := range strings.Fields(s) {
+			trytes = append(trytes, giota.Trytes(f))
+		}
+	}
+
+	txs := make([]giota.Transaction, len(trytes))
+	for i, t := range trytes {
+		tx, err := giota.NewTransaction(t)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = *tx
+	}
+	return txs, nil
+}
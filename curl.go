@@ -28,11 +28,22 @@ package giota
 // constants for Sizes.
 const (
 	stateSize      = 729
-	numberOfRounds = 81
+	numberOfRounds = CurlP81
+)
+
+// numbers of rounds of the variants of Curl-P
+const (
+	// CurlP27 is Curl-P-27, the faster variant with a smaller security
+	// margin.
+	CurlP27 = 27
+
+	// CurlP81 is Curl-P-81, which hashes transactions and addresses.
+	CurlP81 = 81
 )
 
 var (
-	transformC func(Trits)
+	// transformC transforms the state in rounds in C, if cgo is enabled.
+	transformC func(state Trits, rounds int)
 	truthTable = [11]int8{1, 0, -1, 2, 1, -1, 0, 2, -1, 1, 0}
 	indices    [stateSize + 1]int
 )
@@ -52,17 +63,30 @@ func init() {
 // Curl is a sponge function with an internal state of size StateSize.
 // b = r + c, b = StateSize, r = HashSize, c = StateSize - HashSize
 type Curl struct {
-	state Trits
+	state  Trits
+	rounds int
 }
 
-// NewCurl initializes a new instance with an empty state.
+// NewCurl initializes a new instance of Curl-P-81 with an empty state.
 func NewCurl() *Curl {
+	return NewCurlP(numberOfRounds)
+}
+
+// NewCurlP initializes a new instance of Curl-P with rounds rounds, e.g.
+// CurlP27 or CurlP81, with an empty state.
+func NewCurlP(rounds int) *Curl {
 	c := &Curl{
-		state: make(Trits, stateSize),
+		state:  make(Trits, stateSize),
+		rounds: rounds,
 	}
 	return c
 }
 
+// Rounds returns the number of rounds of c.
+func (c *Curl) Rounds() int {
+	return c.rounds
+}
+
 //Squeeze do Squeeze in sponge func.
 func (c *Curl) Squeeze() Trytes {
 	ret := c.state[:HashSize].Trytes()
@@ -73,7 +97,10 @@ func (c *Curl) Squeeze() Trytes {
 
 // Absorb fills the internal state of the sponge with the given trits.
 func (c *Curl) Absorb(inn Trytes) {
-	in := inn.Trits()
+	c.absorbTrits(inn.Trits())
+}
+
+func (c *Curl) absorbTrits(in Trits) {
 	var lenn int
 	for i := 0; i < len(in); i += lenn {
 		lenn = TritHashLength
@@ -90,13 +117,13 @@ func (c *Curl) Absorb(inn Trytes) {
 // Transform does Transform in sponge func.
 func (c *Curl) Transform() {
 	if transformC != nil {
-		transformC(c.state)
+		transformC(c.state, c.rounds)
 		return
 	}
 
 	var cpy [stateSize]int8
 
-	for r := numberOfRounds; r > 0; r-- {
+	for r := c.rounds; r > 0; r-- {
 		copy(cpy[:], c.state)
 		c.state = c.state[:stateSize]
 		for i := 0; i < stateSize; i++ {
@@ -107,6 +134,13 @@ func (c *Curl) Transform() {
 	}
 }
 
+// clone returns a Curl with a copy of the state of c.
+func (c *Curl) clone() *Curl {
+	cc := NewCurlP(c.rounds)
+	copy(cc.state, c.state)
+	return cc
+}
+
 // Reset the internal state of the Curl sponge by filling it with all
 // 0's.
 func (c *Curl) Reset() {
@@ -115,9 +149,7 @@ func (c *Curl) Reset() {
 	}
 }
 
-// Hash returns hash of t.
+// Hash returns the transaction hash of t in the current hash scheme.
 func (t Trytes) Hash() Trytes {
-	c := NewCurl()
-	c.Absorb(t)
-	return c.Squeeze()
+	return hashTrits(CurrentHashScheme().Transaction, t.Trits())
 }
@@ -0,0 +1,117 @@
+package giota
+
+import (
+	"runtime"
+	"sync"
+)
+
+// curlBatchSize is the number of trit slices hashed at once by the bit-sliced
+// Curl, one per bit of the uint64s.
+const curlBatchSize = 64
+
+// HashTransactions returns the hashes of txs in the current hash scheme.
+// They are computed in batches of 64 transactions of the same version,
+// spread over the CPUs. If the scheme hashes transactions with Curl-P, a
+// batch is hashed at once with the bit-sliced Curl transform used by PowGo,
+// otherwise its transactions are hashed one by one.
+func HashTransactions(txs []Transaction) []Trytes {
+	scheme := CurrentHashScheme()
+	var batches [][]int
+	open := make(map[int]int)
+	for i := range txs {
+		v := txs[i].Version
+		b, ok := open[v]
+		if !ok || len(batches[b]) == curlBatchSize {
+			b = len(batches)
+			open[v] = b
+			batches = append(batches, make([]int, 0, curlBatchSize))
+		}
+		batches[b] = append(batches[b], i)
+	}
+
+	hs := make([]Trytes, len(txs))
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for n := 0; n < runtime.GOMAXPROCS(0) && n < len(batches); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trits := make([]Trits, 0, curlBatchSize)
+			for batch := range work {
+				trits = trits[:0]
+				for _, i := range batch {
+					trits = append(trits, txs[i].Trytes().Trits())
+				}
+				if scheme.CurlRounds == 0 {
+					for k, tr := range trits {
+						hs[batch[k]] = hashTrits(scheme.Transaction, tr)
+					}
+					continue
+				}
+				for k, h := range hashBatch(trits, scheme.CurlRounds) {
+					hs[batch[k]] = h
+				}
+			}
+		}()
+	}
+
+	for _, batch := range batches {
+		work <- batch
+	}
+	close(work)
+	wg.Wait()
+	return hs
+}
+
+// ValidateNonces reports for every transaction of txs whether its hash ends
+// with at least mwm zero trits, like HasValidNonce.
+func ValidateNonces(txs []Transaction, mwm int64) []bool {
+	valid := make([]bool, len(txs))
+	for i, h := range HashTransactions(txs) {
+		valid[i] = h.Trits().TrailingZeros() >= mwm
+	}
+	return valid
+}
+
+// hashBatch returns the Curl-P hashes with rounds rounds of up to 64 trit
+// slices of the same length, absorbed like Curl.Absorb does.
+func hashBatch(ts []Trits, rounds int) []Trytes {
+	// the state begins with 0 trits, which are 1 in both the low and high
+	// bits
+	var lmid, hmid [stateSize]uint64
+	for i := range lmid {
+		lmid[i] = hBits
+		hmid[i] = hBits
+	}
+
+	n := len(ts[0])
+	for i := 0; i < n; i += HashSize {
+		end := i + HashSize
+		if end > n {
+			end = n
+		}
+		for k := i; k < end; k++ {
+			var l, h uint64
+			for b, t := range ts {
+				switch t[k] {
+				case -1:
+					l |= 1 << uint(b)
+				case 0:
+					l |= 1 << uint(b)
+					h |= 1 << uint(b)
+				case 1:
+					h |= 1 << uint(b)
+				}
+			}
+			lmid[k-i] = l
+			hmid[k-i] = h
+		}
+		transform64(&lmid, &hmid, rounds)
+	}
+
+	hs := make([]Trytes, len(ts))
+	for b := range ts {
+		hs[b] = seri(&lmid, &hmid, uint(b), 0, HashSize).Trytes()
+	}
+	return hs
+}
@@ -0,0 +1,64 @@
+package giota
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashTransactions(t *testing.T) {
+	defer SetHashScheme(CurlPScheme)
+
+	txs := make([]Transaction, curlBatchSize+3)
+	for i := range txs {
+		txs[i] = testPowTransaction()
+		txs[i].CurrentIndex = int64(i)
+	}
+
+	for _, s := range []*HashScheme{CurlPScheme, CurlP27Scheme, KerlScheme} {
+		if err := SetHashScheme(s); err != nil {
+			t.Fatal(err)
+		}
+
+		hs := HashTransactions(txs)
+		if len(hs) != len(txs) {
+			t.Fatalf("%s: HashTransactions() returned %d hashes for %d transactions", s.Name, len(hs), len(txs))
+		}
+		for i := range txs {
+			if hs[i] != txs[i].Hash() {
+				t.Errorf("%s: hash of transaction %d is %s, want %s", s.Name, i, hs[i], txs[i].Hash())
+			}
+		}
+	}
+
+	if len(HashTransactions(nil)) != 0 {
+		t.Error("HashTransactions(nil) returned hashes")
+	}
+}
+
+func TestValidateNonces(t *testing.T) {
+	e := BestPowEngine()
+	txs := make([]Transaction, 3)
+	for i := range txs {
+		txs[i] = testPowTransaction()
+		txs[i].CurrentIndex = int64(i)
+		if i == 1 {
+			continue
+		}
+
+		r, err := e.Search(context.Background(), txs[i].Trytes(), 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs[i].Nonce = r.Nonce
+	}
+
+	valid := ValidateNonces(txs, 9)
+	for i, v := range valid {
+		if v != txs[i].HasValidNonce(9) {
+			t.Errorf("ValidateNonces() of transaction %d is %v", i, v)
+		}
+	}
+	if !valid[0] || !valid[2] {
+		t.Error("valid nonces were rejected")
+	}
+}
@@ -0,0 +1,331 @@
+package giota
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/decred/base58"
+	"github.com/peterdouglas/bp-go"
+)
+
+// TransactionDescription is the decoded content of a transaction, for
+// people. It is marshaled to JSON and its String method returns it as text.
+type TransactionDescription struct {
+	Hash    Trytes  `json:"hash"`
+	Weight  int64   `json:"weight"`
+	Version int     `json:"version"`
+	Address Address `json:"address"`
+
+	// Value is the value of a transaction of the IOTA layout, the others
+	// have a commitment to their value and the value encrypted.
+	Value                *int64                 `json:"value,omitempty"`
+	Commitment           *PointDescription      `json:"commitment,omitempty"`
+	EncryptedValueLength int                    `json:"encryptedValueLength,omitempty"`
+	RangeProof           *RangeProofDescription `json:"rangeProof,omitempty"`
+
+	Signature *SignatureDescription `json:"signature,omitempty"`
+	Message   string                `json:"message,omitempty"`
+
+	Tag                 Trytes    `json:"tag"`
+	ObsoleteTag         Trytes    `json:"obsoleteTag"`
+	Timestamp           time.Time `json:"timestamp"`
+	AttachmentTimestamp time.Time `json:"attachmentTimestamp"`
+	CurrentIndex        int64     `json:"currentIndex"`
+	LastIndex           int64     `json:"lastIndex"`
+	Bundle              Trytes    `json:"bundle"`
+	TrunkTransaction    Trytes    `json:"trunkTransaction"`
+	BranchTransaction   Trytes    `json:"branchTransaction"`
+	Nonce               Trytes    `json:"nonce"`
+
+	// Errors are the fields which couldn't be decoded.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PointDescription is a point of the secp256k1 curve in hexadecimal.
+type PointDescription struct {
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// RangeProofDescription is the content of a serialized Bulletproofs range
+// proof.
+type RangeProofDescription struct {
+	Length int `json:"length"`
+
+	// Commitments are the points of the proof: Comm, the commitment to the
+	// value, A, S, T1 and T2.
+	Commitments map[string]*PointDescription `json:"commitments,omitempty"`
+
+	// L and R are the lengths of the vectors of the inner product argument.
+	L int `json:"l"`
+	R int `json:"r"`
+
+	// Scalars are the scalars of the proof in hexadecimal: Tau, Th, Mu, the
+	// IPP.A and IPP.B of the inner product argument and the challenges Cy, Cz
+	// and Cx.
+	Scalars map[string]string `json:"scalars,omitempty"`
+}
+
+// SignatureDescription is a Schnorr signature in hexadecimal.
+type SignatureDescription struct {
+	R string `json:"r"`
+	S string `json:"s"`
+}
+
+// BundleDescription is the decoded content of a bundle.
+type BundleDescription struct {
+	// Hash is the bundle hash written by Bundle.Finalize.
+	Hash         Trytes                    `json:"hash"`
+	Transactions []*TransactionDescription `json:"transactions"`
+
+	// Errors are the inconsistencies between the transactions.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Describe decodes every field of t.
+func (t *Transaction) Describe() *TransactionDescription {
+	h := t.Hash()
+	d := &TransactionDescription{
+		Hash:                h,
+		Weight:              h.Trits().TrailingZeros(),
+		Version:             t.Version,
+		Address:             t.Address,
+		Tag:                 t.Tag,
+		ObsoleteTag:         t.ObsoleteTag,
+		Timestamp:           t.Timestamp.UTC(),
+		AttachmentTimestamp: time.Unix(0, t.AttachmentTimestamp.Trits().Int()*int64(time.Millisecond)).UTC(),
+		CurrentIndex:        t.CurrentIndex,
+		LastIndex:           t.LastIndex,
+		Bundle:              t.Bundle,
+		TrunkTransaction:    t.TrunkTransaction,
+		BranchTransaction:   t.BranchTransaction,
+		Nonce:               t.Nonce,
+	}
+
+	if t.Version == LayoutVersionIOTA {
+		v := t.Value.Trits().Int()
+		d.Value = &v
+	} else {
+		d.describeValue(t)
+	}
+	d.describeFragment(t.SignatureMessageFragment)
+	return d
+}
+
+// describeValue decodes the commitment, encrypted value and range proof.
+func (d *TransactionDescription) describeValue(t *Transaction) {
+	if !isEmptyTrytes(t.VectorP) {
+		c := Commitment{Trytes: t.VectorP}
+		p, err := c.Decode()
+		if err != nil {
+			d.Errors = append(d.Errors, "commitment: "+err.Error())
+		} else {
+			d.Commitment = &PointDescription{X: fmt.Sprintf("%x", p.X), Y: fmt.Sprintf("%x", p.Y)}
+		}
+	}
+
+	if !isEmptyTrytes(t.Value) {
+		b, ok := trimmedBytes(t.Value)
+		if !ok {
+			d.Errors = append(d.Errors, "encrypted value: not base58 in trytes")
+		} else {
+			d.EncryptedValueLength = len(base58.Decode(string(b)))
+		}
+	}
+
+	if !isEmptyTrytes(t.RangeProof) {
+		b, ok := trimmedBytes(t.RangeProof)
+		if !ok {
+			d.Errors = append(d.Errors, "range proof: not ASCII in trytes")
+			return
+		}
+
+		var rp bp_go.RangeProof
+		if err := json.Unmarshal(b, &rp); err != nil {
+			d.RangeProof = &RangeProofDescription{Length: len(b)}
+			d.Errors = append(d.Errors, "range proof: "+err.Error())
+			return
+		}
+		d.RangeProof = describeRangeProof(&rp, len(b))
+	}
+}
+
+// describeRangeProof returns the components of rp, which was serialized in
+// length bytes.
+func describeRangeProof(rp *bp_go.RangeProof, length int) *RangeProofDescription {
+	point := func(p bp_go.ECPoint) *PointDescription {
+		return &PointDescription{X: fmt.Sprintf("%x", p.X), Y: fmt.Sprintf("%x", p.Y)}
+	}
+
+	d := &RangeProofDescription{
+		Length: length,
+		Commitments: map[string]*PointDescription{
+			"Comm": point(rp.Comm),
+			"A":    point(rp.A),
+			"S":    point(rp.S),
+			"T1":   point(rp.T1),
+			"T2":   point(rp.T2),
+		},
+		L:       len(rp.IPP.L),
+		R:       len(rp.IPP.R),
+		Scalars: make(map[string]string),
+	}
+	for name, v := range map[string]*big.Int{
+		"Tau":   rp.Tau,
+		"Th":    rp.Th,
+		"Mu":    rp.Mu,
+		"IPP.A": rp.IPP.A,
+		"IPP.B": rp.IPP.B,
+		"Cy":    rp.Cy,
+		"Cz":    rp.Cz,
+		"Cx":    rp.Cx,
+	} {
+		d.Scalars[name] = fmt.Sprintf("%x", v)
+	}
+	return d
+}
+
+// describeFragment decodes a signature or a message from fragment.
+func (d *TransactionDescription) describeFragment(fragment Trytes) {
+	if isEmptyTrytes(fragment) {
+		return
+	}
+
+	b, ok := trimmedBytes(fragment)
+	if ok {
+		if sig := base58.Decode(string(b)); len(sig) == 64 {
+			d.Signature = &SignatureDescription{
+				R: fmt.Sprintf("%x", sig[:32]),
+				S: fmt.Sprintf("%x", sig[32:]),
+			}
+			return
+		}
+		if utf8.Valid(b) {
+			d.Message = string(b)
+			return
+		}
+	}
+	d.Message = strings.TrimRight(string(fragment), "9")
+}
+
+// isEmptyTrytes returns true if t has only 9s.
+func isEmptyTrytes(t Trytes) bool {
+	return strings.Trim(string(t), "9") == ""
+}
+
+// trimmedBytes returns the bytes in t like trytesToBytes, the trailing 9s of
+// t being padding.
+func trimmedBytes(t Trytes) ([]byte, bool) {
+	s := strings.TrimRight(string(t), "9")
+	if len(s)%2 != 0 {
+		s += "9"
+	}
+	return trytesToBytes(Trytes(s))
+}
+
+// String returns d as text, a field per line.
+func (d *TransactionDescription) String() string {
+	var b bytes.Buffer
+	line := func(name string, v interface{}) {
+		fmt.Fprintf(&b, "%-22s %v\n", name+":", v)
+	}
+
+	line("hash", d.Hash)
+	line("weight", d.Weight)
+	line("version", d.Version)
+	line("address", d.Address)
+	if d.Value != nil {
+		line("value", *d.Value)
+	}
+	if d.Commitment != nil {
+		line("commitment x", d.Commitment.X)
+		line("commitment y", d.Commitment.Y)
+	}
+	if d.EncryptedValueLength > 0 {
+		line("encrypted value", fmt.Sprintf("%d bytes", d.EncryptedValueLength))
+	}
+	if d.RangeProof != nil {
+		rp := d.RangeProof
+		line("range proof", fmt.Sprintf("%d bytes", rp.Length))
+		points := make([]string, 0, len(rp.Commitments))
+		for k := range rp.Commitments {
+			points = append(points, k)
+		}
+		sort.Strings(points)
+		for _, k := range points {
+			line("  "+k+" x", rp.Commitments[k].X)
+			line("  "+k+" y", rp.Commitments[k].Y)
+		}
+		if rp.Commitments != nil {
+			line("  L, R", fmt.Sprintf("%d, %d points", rp.L, rp.R))
+		}
+		scalars := make([]string, 0, len(rp.Scalars))
+		for k := range rp.Scalars {
+			scalars = append(scalars, k)
+		}
+		sort.Strings(scalars)
+		for _, k := range scalars {
+			line("  "+k, rp.Scalars[k])
+		}
+	}
+	if d.Signature != nil {
+		line("signature r", d.Signature.R)
+		line("signature s", d.Signature.S)
+	}
+	if d.Message != "" {
+		line("message", d.Message)
+	}
+	line("tag", d.Tag)
+	line("obsolete tag", d.ObsoleteTag)
+	line("timestamp", d.Timestamp.Format(time.RFC3339))
+	line("attachment timestamp", d.AttachmentTimestamp.Format(time.RFC3339Nano))
+	line("index", fmt.Sprintf("%d/%d", d.CurrentIndex, d.LastIndex))
+	line("bundle", d.Bundle)
+	line("trunk", d.TrunkTransaction)
+	line("branch", d.BranchTransaction)
+	line("nonce", d.Nonce)
+	for _, e := range d.Errors {
+		line("error", e)
+	}
+	return b.String()
+}
+
+// Describe decodes every transaction of bs and checks that they make a
+// bundle.
+func (bs Bundle) Describe() *BundleDescription {
+	d := &BundleDescription{Hash: bs.finalizedHash()}
+	for i := range bs {
+		tx := &bs[i]
+		d.Transactions = append(d.Transactions, tx.Describe())
+
+		if tx.CurrentIndex != int64(i) {
+			d.Errors = append(d.Errors, fmt.Sprintf("transaction %d has CurrentIndex %d", i, tx.CurrentIndex))
+		}
+		if tx.LastIndex != int64(len(bs)-1) {
+			d.Errors = append(d.Errors, fmt.Sprintf("transaction %d has LastIndex %d", i, tx.LastIndex))
+		}
+		if tx.Bundle != d.Hash {
+			d.Errors = append(d.Errors, fmt.Sprintf("transaction %d has the bundle hash %s", i, tx.Bundle))
+		}
+	}
+	return d
+}
+
+// String returns d as text.
+func (d *BundleDescription) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "bundle %s, %d transactions\n", d.Hash, len(d.Transactions))
+	for _, e := range d.Errors {
+		fmt.Fprintf(&b, "error: %s\n", e)
+	}
+	for _, t := range d.Transactions {
+		fmt.Fprintf(&b, "\n%s", t)
+	}
+	return b.String()
+}
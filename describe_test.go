@@ -0,0 +1,122 @@
+package giota
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/decred/base58"
+	"github.com/peterdouglas/bp-go"
+)
+
+func TestTransactionDescribe(t *testing.T) {
+	p := bp_go.ECPoint{X: big.NewInt(0xab), Y: big.NewInt(0xcd)}
+	rp, err := json.Marshal(bp_go.RangeProof{
+		Comm: p, A: p, S: p, T1: p, T2: p,
+		Tau: big.NewInt(10),
+		IPP: bp_go.InnerProdArg{L: []bp_go.ECPoint{p, p}, R: []bp_go.ECPoint{p, p}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := AsciiToTrytes(string(rp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encValue, err := AsciiToTrytes(base58.Encode([]byte("encrypted")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := AsciiToTrytes("hello tangle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := testPowTransaction()
+	tx.Value = encValue
+	tx.RangeProof = proof
+	tx.SignatureMessageFragment = msg
+	tx.AttachmentTimestamp = Int2Trits(1500000000123, 27).Trytes()
+
+	d := tx.Describe()
+	switch {
+	case d.Hash != tx.Hash():
+		t.Error("wrong hash")
+	case d.EncryptedValueLength != len("encrypted"):
+		t.Errorf("encrypted value length is %d", d.EncryptedValueLength)
+	case d.RangeProof == nil || d.RangeProof.Length != len(rp) || d.RangeProof.Commitments["T2"].X != "ab" ||
+		d.RangeProof.L != 2 || d.RangeProof.R != 2 || d.RangeProof.Scalars["Tau"] != "a":
+		t.Errorf("range proof is %+v", d.RangeProof)
+	case d.Message != "hello tangle" || d.Signature != nil:
+		t.Errorf("message is %q", d.Message)
+	case d.AttachmentTimestamp.UnixNano() != 1500000000123000000:
+		t.Errorf("attachment timestamp is %v", d.AttachmentTimestamp)
+	}
+
+	tx.RangeProof, _ = AsciiToTrytes(`{"A":"abc"}`)
+	if d = tx.Describe(); len(d.Errors) != 1 || !strings.HasPrefix(d.Errors[0], "range proof:") ||
+		d.RangeProof == nil || d.RangeProof.Commitments != nil {
+		t.Errorf("range proof which is not a proof is %+v, errors are %v", d.RangeProof, d.Errors)
+	}
+	tx.RangeProof = proof
+
+	sig := make([]byte, 64)
+	sig[0], sig[63] = 0xab, 0xcd
+	tx.SignatureMessageFragment, _ = AsciiToTrytes(base58.Encode(sig))
+	d = tx.Describe()
+	if d.Signature == nil || !strings.HasPrefix(d.Signature.R, "ab") || !strings.HasSuffix(d.Signature.S, "cd") {
+		t.Errorf("signature is %+v", d.Signature)
+	}
+
+	// fields which aren't bytes are reported, not decoded
+	tx.VectorP = "ZZZZ"
+	tx.RangeProof = "ZZZZ"
+	if d = tx.Describe(); len(d.Errors) != 2 || d.Commitment != nil || d.RangeProof != nil {
+		t.Errorf("errors are %v", d.Errors)
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil || !strings.Contains(string(b), `"hash":"`+string(d.Hash)+`"`) {
+		t.Errorf("JSON is %s, %v", b, err)
+	}
+	if !strings.Contains(d.String(), "signature r:") {
+		t.Errorf("text is %s", d)
+	}
+}
+
+func TestBundleDescribe(t *testing.T) {
+	tx := testIOTATransaction()
+	tx.CurrentIndex, tx.LastIndex = 0, 1
+	bs := Bundle{tx, tx}
+
+	d := bs.Describe()
+	if len(d.Transactions) != 2 || *d.Transactions[0].Value != -42 {
+		t.Fatalf("description is %+v", d)
+	}
+
+	// transaction 1 has both a wrong index and a wrong bundle hash
+	var errs1 []string
+	for _, e := range d.Errors {
+		if strings.HasPrefix(e, "transaction 1 ") {
+			errs1 = append(errs1, e)
+		}
+	}
+	if len(errs1) != 2 || !strings.Contains(errs1[0], "CurrentIndex") || !strings.Contains(errs1[1], "bundle hash") {
+		t.Errorf("errors are %v", d.Errors)
+	}
+}
+
+func TestBundleDescribeFinalized(t *testing.T) {
+	bs := Bundle{testIOTATransaction(), testIOTATransaction()}
+	bs.Finalize(nil)
+
+	if d := bs.Describe(); len(d.Errors) != 0 || d.Hash != bs[0].Bundle {
+		t.Errorf("finalized bundle %s has errors %v", d.Hash, d.Errors)
+	}
+
+	bs[1].Bundle = EmptyHash
+	if d := bs.Describe(); len(d.Errors) != 1 || !strings.Contains(d.Errors[0], "bundle hash") {
+		t.Errorf("errors are %v", d.Errors)
+	}
+}
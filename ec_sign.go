@@ -28,38 +28,59 @@ package giota
 import (
 	"github.com/NebulousLabs/hdkey"
 	"fmt"
-	"unicode/utf8"
-	"strings"
 	"math/big"
+	"sync"
 )
 
+// mKey caches the master key of the seed mKeySeed, the last seed used.
 var (
-	mKey *hdkey.HDKey = nil
+	mKeyMu   sync.Mutex
+	mKeySeed Trytes
+	mKey     *hdkey.HDKey
 )
 
+// masterKey returns the master key of seed.
+func masterKey(seed Trytes) (*hdkey.HDKey, error) {
+	mKeyMu.Lock()
+	defer mKeyMu.Unlock()
+
+	if mKey != nil && mKeySeed == seed {
+		return mKey, nil
+	}
+
+	bytesSec, err := seed.Trits().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	key, err := hdkey.NewMaster(bytesSec, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	mKey, mKeySeed = key, seed
+	return key, nil
+}
+
 
 // NewKey takes a seed encoded as Trytes, an index and a security
 // level to derive a private key returned as Trytes
 func NewPublicKey(seed Trytes, index int) (Trytes, error) {
-	bytesSec, err := seed.Trits().Bytes()
+	master, err := masterKey(seed)
 	if err != nil {
 		return "", err
 	}
 
-	if mKey == nil {
-		key, err := hdkey.NewMaster(bytesSec, nil, 1)
-		if err != nil {
-			return "", err
-		}
-		mKey = key
-	}
-
-	pubKey, err := mKey.Child(uint32(index))
+	pubKey, err := master.Child(uint32(index))
 	if err != nil {
 		return "", err
 	}
 
-	pkCompressed := pubKey.PublicKey().Compress()
+	return publicKeyTrytes(pubKey)
+}
+
+// publicKeyTrytes returns the compressed public key of key in trytes, which
+// are the trytes of its address.
+func publicKeyTrytes(key *hdkey.HDKey) (Trytes, error) {
+	pkCompressed := key.PublicKey().Compress()
 	pkInt := new(big.Int).SetBytes(pkCompressed[:])
 	keyTrit := make([]byte, 48)
 	copy(keyTrit, pkInt.Bytes())
@@ -68,24 +89,16 @@ func NewPublicKey(seed Trytes, index int) (Trytes, error) {
 		return "", err
 	}
 
-	return trits.Trytes(), err
+	return trits.Trytes(), nil
 }
 
 func NewSecKey(seed Trytes, index int) (*hdkey.HDKey, error) {
-	bytesSec, err := seed.Trits().Bytes()
+	master, err := masterKey(seed)
 	if err != nil {
 		return &hdkey.HDKey{}, err
 	}
 
-	if mKey == nil {
-		key, err := hdkey.NewMaster(bytesSec, nil, 1)
-		if err != nil {
-			return &hdkey.HDKey{}, err
-		}
-		mKey = key
-	}
-
-	secKey, err := mKey.Child(uint32(index))
+	secKey, err := master.Child(uint32(index))
 	if err != nil {
 		return &hdkey.HDKey{}, err
 	}
@@ -140,23 +153,13 @@ func AsciiToTrytes(input string) (Trytes, error) {
 }
 
 func TrytesToAscii(input Trytes) (string, error) {
-	var tempOutput string
-	var err error
 	if len(input) % 2 != 0 {
-		err = fmt.Errorf("Length was not divisble by 2, invalid tryte")
-		return "", err
+		return "", fmt.Errorf("Length was not divisble by 2, invalid tryte")
 	}
 
-	for i := 0; i < len(input); i+= 2  {
-		firstVal, _ := utf8.DecodeRuneInString(string(input[i:]))
-		secondVal, _ := utf8.DecodeRuneInString(string(input[i + 1:]))
-		ind1 := strings.Index(TryteAlphabet, string(firstVal))
-		ind2 := strings.Index(TryteAlphabet, string(secondVal))
-		decimalVal := ind1 + ind2 * 27
-		tempByte := make([]byte, 1)
-		_ = utf8.EncodeRune(tempByte, rune(decimalVal))
-		tempOutput += string(tempByte)
+	b, ok := trytesToBytes(input)
+	if !ok {
+		return "", fmt.Errorf("Trytes don't encode bytes, invalid tryte")
 	}
-
-	return tempOutput, err
+	return string(b), nil
 }
\ No newline at end of file
@@ -0,0 +1,165 @@
+package fakenode
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+// nullTransaction is returned by getTrytes for unknown hashes.
+var nullTransaction = giota.Transaction{Timestamp: time.Unix(0, 0)}
+
+// Backend is the ledger served by a Handler. *tangle.Tangle implements it.
+type Backend interface {
+	Milestone() (giota.Trytes, int64)
+	Tips() []giota.Trytes
+	Find(req *giota.FindTransactionsRequest) []giota.Trytes
+	Transaction(h giota.Trytes) (giota.Transaction, bool)
+	Balances(adrs []giota.Address) []string
+	InclusionStates(hashes []giota.Trytes) []bool
+	TransactionsToApprove(depth int64, reference giota.Trytes) (giota.Trytes, giota.Trytes, error)
+	Store(txs []giota.Transaction) error
+	CheckConsistency(tails []giota.Trytes) (bool, string)
+}
+
+// Handler serves the IRI API commands used by giota.API from a Backend.
+// It implements giota.NodeService and serves it with a giota.Server.
+type Handler struct {
+	giota.UnimplementedNodeService
+
+	// MinWeightMagnitude is the minimum PoW transactions need to be stored.
+	MinWeightMagnitude int64
+
+	// Pow is used by attachToTangle. If it is nil, the bundles are attached
+	// by a BundlePow of the best PowEngine of giota.
+	Pow giota.PowFunc
+
+	// AppName is returned by getNodeInfo.
+	AppName string
+
+	backend  Backend
+	server   *giota.Server
+	attachMu sync.Mutex
+}
+
+// NewHandler returns a Handler serving b.
+func NewHandler(b Backend) *Handler {
+	h := &Handler{
+		MinWeightMagnitude: giota.DefaultMinWeightMagnitude,
+		AppName:            "fakenode",
+		backend:            b,
+	}
+	h.server = giota.NewServer(h)
+	return h
+}
+
+// ServeHTTP handles an IRI API command.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.server.ServeHTTP(w, r)
+}
+
+// GetNodeInfo reports the latest milestone of the backend.
+func (h *Handler) GetNodeInfo(*giota.GetNodeInfoRequest) (*giota.GetNodeInfoResponse, error) {
+	ms, idx := h.backend.Milestone()
+	return &giota.GetNodeInfoResponse{
+		AppName:                            h.AppName,
+		AppVersion:                         "1.0.0",
+		LatestMilestone:                    ms,
+		LatestMilestoneIndex:               idx,
+		LatestSolidSubtangleMilestone:      ms,
+		LatestSolidSubtangleMilestoneIndex: idx,
+		Tips:                               int64(len(h.backend.Tips())),
+		Time:                               time.Now().UnixNano() / int64(time.Millisecond),
+	}, nil
+}
+
+// GetTips returns the tips of the backend.
+func (h *Handler) GetTips(*giota.GetTipsRequest) (*giota.GetTipsResponse, error) {
+	return &giota.GetTipsResponse{Hashes: h.backend.Tips()}, nil
+}
+
+// FindTransactions searches the backend.
+func (h *Handler) FindTransactions(req *giota.FindTransactionsRequest) (*giota.FindTransactionsResponse, error) {
+	return &giota.FindTransactionsResponse{Hashes: h.backend.Find(req)}, nil
+}
+
+// GetTrytes returns a transaction made of 9s for unknown hashes like IRI does.
+func (h *Handler) GetTrytes(req *giota.GetTrytesRequest) (*giota.GetTrytesResponse, error) {
+	txs := make([]giota.Transaction, len(req.Hashes))
+	for i, hash := range req.Hashes {
+		tx, exist := h.backend.Transaction(hash)
+		if !exist {
+			tx = nullTransaction
+		}
+		txs[i] = tx
+	}
+	return &giota.GetTrytesResponse{Trytes: txs}, nil
+}
+
+// GetBalances returns the confirmed balances of the backend.
+func (h *Handler) GetBalances(req *giota.GetBalancesRequest) (*giota.GetBalancesResponse, error) {
+	ms, idx := h.backend.Milestone()
+	return &giota.GetBalancesResponse{
+		Balances:       h.backend.Balances(req.Addresses),
+		Milestone:      ms,
+		MilestoneIndex: idx,
+	}, nil
+}
+
+// GetInclusionStates returns whether transactions are confirmed.
+func (h *Handler) GetInclusionStates(req *giota.GetInclusionStatesRequest) (*giota.GetInclusionStatesResponse, error) {
+	return &giota.GetInclusionStatesResponse{States: h.backend.InclusionStates(req.Transactions)}, nil
+}
+
+// GetTransactionsToApprove selects tips with the backend.
+func (h *Handler) GetTransactionsToApprove(req *giota.GetTransactionsToApproveRequest) (*giota.GetTransactionsToApproveResponse, error) {
+	trunk, branch, err := h.backend.TransactionsToApprove(req.Depth, req.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return &giota.GetTransactionsToApproveResponse{
+		TrunkTransaction:  trunk,
+		BranchTransaction: branch,
+	}, nil
+}
+
+// AttachToTangle does the PoW of one request at a time.
+func (h *Handler) AttachToTangle(req *giota.AttachToTangleRequest) (*giota.AttachToTangleResponse, error) {
+	h.attachMu.Lock()
+	defer h.attachMu.Unlock()
+
+	return giota.LocalAttachToTangle(req, h.Pow)
+}
+
+// InterruptAttachingToTangle does nothing.
+func (h *Handler) InterruptAttachingToTangle(*giota.InterruptAttachingToTangleRequest) error {
+	return nil
+}
+
+// BroadcastTransactions stores the transactions like StoreTransactions.
+func (h *Handler) BroadcastTransactions(req *giota.BroadcastTransactionsRequest) error {
+	return h.store(req.Trytes)
+}
+
+// StoreTransactions stores transactions with valid nonces in the backend.
+func (h *Handler) StoreTransactions(req *giota.StoreTransactionsRequest) error {
+	return h.store(req.Trytes)
+}
+
+func (h *Handler) store(txs []giota.Transaction) error {
+	for i, valid := range giota.ValidateNonces(txs, h.MinWeightMagnitude) {
+		if !valid {
+			return fmt.Errorf("transaction %d has an invalid nonce", i)
+		}
+	}
+	return h.backend.Store(txs)
+}
+
+// CheckConsistency checks tails with the backend.
+func (h *Handler) CheckConsistency(req *giota.CheckConsistencyRequest) (*giota.CheckConsistencyResponse, error) {
+	state, info := h.backend.CheckConsistency(req.Tails)
+	return &giota.CheckConsistencyResponse{State: state, Info: info}, nil
+}
@@ -0,0 +1,108 @@
+// Package fakenode is an in-process IRI node for tests and local development.
+// It serves the commands used by giota.API over httptest from an in-memory tangle,
+// does real PoW on attachToTangle and confirms transactions when the test
+// advances the milestone. New and confirmed transactions can be followed with a
+// giota.Subscriber on the feed started by Feed.
+package fakenode
+
+import (
+	"net/http/httptest"
+	"sync"
+
+	"github.com/peterdouglas/giota"
+	"github.com/peterdouglas/giota/tangle"
+)
+
+// Node is a fake IRI node.
+type Node struct {
+	*Handler
+
+	tangle *tangle.Tangle
+	server *httptest.Server
+
+	feedMu sync.Mutex
+	feed   *giota.FeedServer
+}
+
+// New starts a fake node listening on a local address. Bundles are not
+// validated so that tests can store transactions without signatures or proofs.
+func New() *Node {
+	n := &Node{}
+	n.tangle = tangle.New(tangle.Config{
+		Validate:   func(giota.Bundle) error { return nil },
+		ConfirmAll: true,
+		OnTransaction: func(tx *giota.Transaction) {
+			if f := n.feedServer(); f != nil {
+				f.PublishTransaction(tx)
+			}
+		},
+		OnConfirmation: func(index int64, tx *giota.Transaction) {
+			if f := n.feedServer(); f != nil {
+				f.PublishConfirmation(index, tx)
+			}
+		},
+	})
+	n.Handler = NewHandler(n.tangle)
+
+	n.server = httptest.NewServer(n.Handler)
+	return n
+}
+
+// URL returns the endpoint of the node.
+func (n *Node) URL() string {
+	return n.server.URL
+}
+
+// API returns an API talking to the node.
+func (n *Node) API() *giota.API {
+	return giota.NewAPI(n.server.URL, n.server.Client())
+}
+
+// Close shuts the node down.
+func (n *Node) Close() {
+	n.server.Close()
+
+	if f := n.feedServer(); f != nil {
+		f.Close()
+	}
+}
+
+func (n *Node) feedServer() *giota.FeedServer {
+	n.feedMu.Lock()
+	defer n.feedMu.Unlock()
+
+	return n.feed
+}
+
+// Feed starts publishing new and confirmed transactions on a local TCP feed
+// and returns its address, to be used with giota.DialFeed.
+func (n *Node) Feed() (string, error) {
+	n.feedMu.Lock()
+	defer n.feedMu.Unlock()
+
+	if n.feed == nil {
+		f, err := giota.NewFeedServer("127.0.0.1:0")
+		if err != nil {
+			return "", err
+		}
+		n.feed = f
+	}
+	return n.feed.Addr(), nil
+}
+
+// Tangle returns the ledger of the node.
+func (n *Node) Tangle() *tangle.Tangle {
+	return n.tangle
+}
+
+// AdvanceMilestone issues a new milestone which confirms all the transactions
+// stored and returns its index.
+func (n *Node) AdvanceMilestone() int64 {
+	_, idx := n.tangle.IssueMilestone()
+	return idx
+}
+
+// Transaction returns the stored transaction with hash h.
+func (n *Node) Transaction(h giota.Trytes) (giota.Transaction, bool) {
+	return n.tangle.Transaction(h)
+}
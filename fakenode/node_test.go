@@ -0,0 +1,127 @@
+package fakenode
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+var testAddress = giota.Address(strings.Repeat("A", 81))
+
+func testBundle() []giota.Transaction {
+	now := time.Unix(time.Now().Unix(), 0)
+	return []giota.Transaction{
+		{Address: testAddress, Bundle: giota.EmptyHash, Timestamp: now, CurrentIndex: 0, LastIndex: 1},
+		{Address: testAddress, Bundle: giota.EmptyHash, Timestamp: now, CurrentIndex: 1, LastIndex: 1},
+	}
+}
+
+func TestNodeSendAndConfirm(t *testing.T) {
+	n := New()
+	defer n.Close()
+
+	api := n.API()
+	if err := giota.SendTrytes(api, giota.Depth, testBundle(), n.MinWeightMagnitude, nil); err != nil {
+		t.Fatalf("SendTrytes() expected err to be nil but got %v", err)
+	}
+
+	ft, err := api.FindTransactions(&giota.FindTransactionsRequest{Addresses: []giota.Address{testAddress}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ft.Hashes) != 2 {
+		t.Fatalf("FindTransactions() returned %d hashes, expected 2", len(ft.Hashes))
+	}
+
+	gt, err := api.GetTrytes(ft.Hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tx := range gt.Trytes {
+		if tx.TrunkTransaction == giota.EmptyHash && tx.CurrentIndex == 0 {
+			t.Errorf("transaction %d was not chained to the bundle on attach", i)
+		}
+	}
+
+	states, err := api.GetLatestInclusion(ft.Hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if states[0] || states[1] {
+		t.Error("transactions are confirmed before a milestone")
+	}
+
+	if idx := n.AdvanceMilestone(); idx != 1 {
+		t.Errorf("AdvanceMilestone() returned index %d, expected 1", idx)
+	}
+
+	states, err = api.GetLatestInclusion(ft.Hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !states[0] || !states[1] {
+		t.Error("transactions are not confirmed after a milestone")
+	}
+}
+
+func TestNodeRejectsInvalidNonce(t *testing.T) {
+	n := New()
+	defer n.Close()
+	n.MinWeightMagnitude = 20
+
+	if err := n.API().StoreTransactions(testBundle()); err == nil {
+		t.Error("StoreTransactions() accepted transactions without PoW")
+	}
+}
+
+func TestNodeUnknownCommand(t *testing.T) {
+	n := New()
+	defer n.Close()
+
+	if _, err := n.API().GetNeighbors(); err == nil {
+		t.Error("GetNeighbors() expected an error for an unknown command")
+	}
+}
+
+func TestNodeFeed(t *testing.T) {
+	n := New()
+	defer n.Close()
+
+	addr, err := n.Feed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := giota.DialFeed(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := giota.NewSubscriber(tr)
+	defer s.Close()
+
+	sub, err := s.SubscribeAddress(testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := giota.SendTrytes(n.API(), giota.Depth, testBundle(), n.MinWeightMagnitude, nil); err != nil {
+		t.Fatal(err)
+	}
+	n.AdvanceMilestone()
+
+	var txs, confirmations int
+	for txs < 2 || confirmations < 2 {
+		select {
+		case e := <-sub.C:
+			switch {
+			case e.Transaction != nil:
+				txs++
+			case e.Confirmation != nil:
+				confirmations++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d transactions and %d confirmations, expected 2 of each", txs, confirmations)
+		}
+	}
+}
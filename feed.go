@@ -0,0 +1,307 @@
+package giota
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Topics of the transaction feed. Like IRI, the feed also publishes the
+// confirmed transactions of an address on the topic named by the address.
+const (
+	// TopicTransaction publishes "tx_trytes <trytes> <hash>" for every new transaction.
+	TopicTransaction = "tx_trytes"
+
+	// TopicConfirmation publishes "sn <milestone index> <hash> <address> <trunk> <branch> <bundle>"
+	// for every transaction confirmed by a milestone.
+	TopicConfirmation = "sn"
+)
+
+// ErrSubscriberClosed is returned by Subscriber methods after Close.
+var ErrSubscriberClosed = errors.New("subscriber is closed")
+
+// FeedTransport carries the messages of a transaction feed, e.g. a ZMQ socket.
+// A message is a topic followed by space separated fields.
+type FeedTransport interface {
+	// Subscribe asks for the messages of topic. Topics match messages by prefix.
+	Subscribe(topic string) error
+
+	// Receive blocks until the next message arrives.
+	Receive() (string, error)
+
+	Close() error
+}
+
+// TransactionEvent is a new transaction received from the feed.
+type TransactionEvent struct {
+	Hash        Trytes
+	Transaction Transaction
+}
+
+// ConfirmationEvent is a transaction confirmed by a milestone.
+type ConfirmationEvent struct {
+	MilestoneIndex int64
+	Hash           Trytes
+	Address        Address
+
+	// Bundle is empty if the event comes from an address topic.
+	Bundle Trytes
+}
+
+// FeedEvent is an event of the feed. Exactly one of its fields is set.
+type FeedEvent struct {
+	Transaction  *TransactionEvent
+	Confirmation *ConfirmationEvent
+}
+
+func (e *FeedEvent) matches(adr Address, bundle Trytes) bool {
+	switch {
+	case e.Transaction != nil:
+		return (adr == "" || e.Transaction.Transaction.Address == adr) &&
+			(bundle == "" || e.Transaction.Transaction.Bundle == bundle)
+	case e.Confirmation != nil:
+		return (adr == "" || e.Confirmation.Address == adr) &&
+			(bundle == "" || e.Confirmation.Bundle == bundle)
+	}
+	return false
+}
+
+// ParseFeedMessage parses a message of the feed. It returns nil and no error
+// for messages of unknown topics.
+func ParseFeedMessage(msg string) (*FeedEvent, error) {
+	fields := strings.Fields(msg)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case fields[0] == TopicTransaction:
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid %s message", TopicTransaction)
+		}
+		tx, err := NewTransaction(Trytes(fields[1]))
+		if err != nil {
+			return nil, err
+		}
+		return &FeedEvent{Transaction: &TransactionEvent{Hash: Trytes(fields[2]), Transaction: *tx}}, nil
+
+	case fields[0] == TopicConfirmation:
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid %s message", TopicConfirmation)
+		}
+		idx, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &FeedEvent{Confirmation: &ConfirmationEvent{
+			MilestoneIndex: idx,
+			Hash:           Trytes(fields[2]),
+			Address:        Address(fields[3]),
+			Bundle:         Trytes(fields[6]),
+		}}, nil
+
+	case len(fields[0]) == 81 && len(fields) == 3:
+		if _, err := ToAddress(fields[0]); err != nil {
+			return nil, nil
+		}
+		idx, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &FeedEvent{Confirmation: &ConfirmationEvent{
+			MilestoneIndex: idx,
+			Hash:           Trytes(fields[1]),
+			Address:        Address(fields[0]),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// Subscription receives the events of the feed matching its filter.
+type Subscription struct {
+	// C is closed when the subscription or its Subscriber is closed.
+	C <-chan FeedEvent
+
+	c       chan FeedEvent
+	adr     Address
+	bundle  Trytes
+	s       *Subscriber
+	closing chan struct{}
+
+	// mu guards sends on c against closing it
+	mu     sync.Mutex
+	closed bool
+}
+
+// Close stops the subscription.
+func (sub *Subscription) Close() {
+	sub.s.remove(sub)
+}
+
+func (sub *Subscription) send(e *FeedEvent) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	select {
+	case sub.c <- *e:
+	case <-sub.closing:
+	}
+}
+
+func (sub *Subscription) close() {
+	close(sub.closing)
+
+	sub.mu.Lock()
+	sub.closed = true
+	close(sub.c)
+	sub.mu.Unlock()
+}
+
+// Subscriber dispatches the events of a feed to subscriptions.
+// Slow subscriptions hold back the others since events are not dropped.
+type Subscriber struct {
+	transport FeedTransport
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	err  error
+	done chan struct{}
+
+	topicsMu sync.Mutex
+	topics   map[string]bool
+}
+
+// NewSubscriber starts reading the feed of t.
+func NewSubscriber(t FeedTransport) *Subscriber {
+	s := &Subscriber{
+		transport: t,
+		subs:      make(map[*Subscription]struct{}),
+		topics:    make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *Subscriber) run() {
+	var err error
+	for {
+		var msg string
+		if msg, err = s.transport.Receive(); err != nil {
+			break
+		}
+
+		// malformed messages are skipped
+		if e, perr := ParseFeedMessage(msg); perr == nil && e != nil {
+			s.dispatch(e)
+		}
+	}
+
+	s.mu.Lock()
+	s.err = err
+	for sub := range s.subs {
+		delete(s.subs, sub)
+		sub.close()
+	}
+	s.mu.Unlock()
+	close(s.done)
+}
+
+func (s *Subscriber) dispatch(e *FeedEvent) {
+	s.mu.Lock()
+	subs := make([]*Subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		if e.matches(sub.adr, sub.bundle) {
+			subs = append(subs, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.send(e)
+	}
+}
+
+func (s *Subscriber) subscribe(adr Address, bundle Trytes, topics ...string) (*Subscription, error) {
+	// the transport is subscribed without holding s.mu so that events keep
+	// being dispatched meanwhile
+	s.topicsMu.Lock()
+	for _, t := range topics {
+		if s.topics[t] {
+			continue
+		}
+		if err := s.transport.Subscribe(t); err != nil {
+			s.topicsMu.Unlock()
+			return nil, err
+		}
+		s.topics[t] = true
+	}
+	s.topicsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil, ErrSubscriberClosed
+	default:
+	}
+
+	c := make(chan FeedEvent, 16)
+	sub := &Subscription{C: c, c: c, adr: adr, bundle: bundle, s: s, closing: make(chan struct{})}
+	s.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (s *Subscriber) remove(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subs[sub]; !ok {
+		return
+	}
+	delete(s.subs, sub)
+	sub.close()
+}
+
+// SubscribeAll receives all new and confirmed transactions.
+func (s *Subscriber) SubscribeAll() (*Subscription, error) {
+	return s.subscribe("", "", TopicTransaction, TopicConfirmation)
+}
+
+// SubscribeAddress receives the new and confirmed transactions of adr.
+func (s *Subscriber) SubscribeAddress(adr Address) (*Subscription, error) {
+	return s.subscribe(adr, "", TopicTransaction, TopicConfirmation)
+}
+
+// SubscribeBundle receives the new and confirmed transactions of bundle.
+func (s *Subscriber) SubscribeBundle(bundle Trytes) (*Subscription, error) {
+	return s.subscribe("", bundle, TopicTransaction, TopicConfirmation)
+}
+
+// Close closes the transport and all subscriptions.
+func (s *Subscriber) Close() error {
+	s.mu.Lock()
+	for sub := range s.subs {
+		delete(s.subs, sub)
+		sub.close()
+	}
+	s.mu.Unlock()
+
+	err := s.transport.Close()
+	<-s.done
+	return err
+}
+
+// Err returns the error which stopped the Subscriber, if any.
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
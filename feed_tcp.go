@@ -0,0 +1,274 @@
+package giota
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// The TCP feed is a stand-in for the ZMQ feed of IRI. Clients send
+// "subscribe <topic>" lines, which the server acknowledges with a
+// "subscribed <topic>" line, and receive the matching messages one per line.
+
+// tcpFeed is a FeedTransport over a TCP connection to a FeedServer.
+type tcpFeed struct {
+	conn  net.Conn
+	msgs  chan string
+	acks  chan struct{}
+	err   error
+	subMu sync.Mutex
+}
+
+// DialFeed connects to the TCP feed served by a FeedServer at addr.
+func DialFeed(addr string) (FeedTransport, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &tcpFeed{
+		conn: conn,
+		msgs: make(chan string, 64),
+		acks: make(chan struct{}, 1),
+	}
+	go f.read()
+	return f, nil
+}
+
+func (f *tcpFeed) read() {
+	r := bufio.NewReader(f.conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			f.err = err
+			close(f.msgs)
+			close(f.acks)
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "subscribed ") {
+			f.acks <- struct{}{}
+			continue
+		}
+		f.msgs <- line
+	}
+}
+
+// Subscribe returns once the server has acknowledged the subscription.
+func (f *tcpFeed) Subscribe(topic string) error {
+	f.subMu.Lock()
+	defer f.subMu.Unlock()
+
+	if _, err := fmt.Fprintf(f.conn, "subscribe %s\n", topic); err != nil {
+		return err
+	}
+	if _, ok := <-f.acks; !ok {
+		return f.err
+	}
+	return nil
+}
+
+func (f *tcpFeed) Receive() (string, error) {
+	msg, ok := <-f.msgs
+	if !ok {
+		return "", f.err
+	}
+	return msg, nil
+}
+
+func (f *tcpFeed) Close() error {
+	return f.conn.Close()
+}
+
+// feedClientQueue is the number of messages queued for a client. Clients
+// falling further behind are disconnected rather than stalling publishers.
+const feedClientQueue = 1024
+
+// feedClient is a client of a FeedServer. Its messages are queued and
+// written by its own goroutine.
+type feedClient struct {
+	conn net.Conn
+	out  chan string
+	done chan struct{}
+	once sync.Once
+
+	mu     sync.Mutex
+	topics []string
+}
+
+func newFeedClient(conn net.Conn) *feedClient {
+	c := &feedClient{
+		conn: conn,
+		out:  make(chan string, feedClientQueue),
+		done: make(chan struct{}),
+	}
+	go c.write()
+	return c
+}
+
+// send queues the line msg and returns false if the queue is full.
+func (c *feedClient) send(msg string) bool {
+	select {
+	case c.out <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// write writes the queued lines until c is closed.
+func (c *feedClient) write() {
+	for {
+		select {
+		case msg := <-c.out:
+			if _, err := fmt.Fprintf(c.conn, "%s\n", msg); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *feedClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *feedClient) subscribed(msg string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range c.topics {
+		if strings.HasPrefix(msg, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeedServer publishes a transaction feed to TCP clients.
+type FeedServer struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	clients map[*feedClient]struct{}
+}
+
+// NewFeedServer starts a FeedServer listening on addr.
+func NewFeedServer(addr string) (*FeedServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FeedServer{
+		ln:      ln,
+		clients: make(map[*feedClient]struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *FeedServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *FeedServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		c := newFeedClient(conn)
+		s.mu.Lock()
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		go s.read(c)
+	}
+}
+
+// read handles the subscriptions of c until it disconnects.
+func (s *FeedServer) read(c *feedClient) {
+	sc := bufio.NewScanner(c.conn)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), " ", 2)
+		if fields[0] != "subscribe" {
+			continue
+		}
+
+		topic := ""
+		if len(fields) == 2 {
+			topic = fields[1]
+		}
+		c.mu.Lock()
+		c.topics = append(c.topics, topic)
+		c.mu.Unlock()
+		if !c.send("subscribed " + topic) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	c.close()
+}
+
+// Publish sends a message made of topic and fields to the subscribed clients.
+// It doesn't wait for the clients, which are disconnected if too many of their
+// messages are pending.
+func (s *FeedServer) Publish(topic string, fields ...string) {
+	msg := strings.Join(append([]string{topic}, fields...), " ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		if !c.subscribed(msg) {
+			continue
+		}
+		if !c.send(msg) {
+			delete(s.clients, c)
+			c.close()
+		}
+	}
+}
+
+// PublishTransaction publishes a new transaction.
+func (s *FeedServer) PublishTransaction(tx *Transaction) {
+	s.Publish(TopicTransaction, string(tx.Trytes()), string(tx.Hash()))
+}
+
+// PublishConfirmation publishes the confirmation of tx by the milestone at index,
+// on the confirmation topic and on the topic of its address.
+func (s *FeedServer) PublishConfirmation(index int64, tx *Transaction) {
+	idx := fmt.Sprint(index)
+	h := string(tx.Hash())
+	s.Publish(TopicConfirmation, idx, h, string(tx.Address),
+		string(tx.TrunkTransaction), string(tx.BranchTransaction), string(tx.Bundle))
+	s.Publish(string(tx.Address), h, idx)
+}
+
+// Close stops the server and disconnects all clients.
+func (s *FeedServer) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		delete(s.clients, c)
+		c.close()
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package giota
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFeedMessage(t *testing.T) {
+	tx := Transaction{Address: Address(strings.Repeat("A", 81)), Bundle: EmptyHash, Timestamp: time.Unix(1500000000, 0)}
+	adr := strings.Repeat("A", 81)
+	h := strings.Repeat("H", 81)
+
+	tests := []struct {
+		name         string
+		msg          string
+		transaction  bool
+		confirmation bool
+		err          bool
+	}{
+		{name: "transaction", msg: "tx_trytes " + string(tx.Trytes()) + " " + string(tx.Hash()), transaction: true},
+		{name: "confirmation", msg: "sn 12 " + h + " " + adr + " " + h + " " + h + " " + h, confirmation: true},
+		{name: "address", msg: adr + " " + h + " 12", confirmation: true},
+		{name: "unknown topic", msg: "lmi 11 12"},
+		{name: "empty", msg: ""},
+		{name: "short transaction", msg: "tx_trytes ABC", err: true},
+		{name: "bad milestone", msg: "sn X " + h + " " + adr + " " + h + " " + h + " " + h, err: true},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseFeedMessage(tt.msg)
+		switch {
+		case (err != nil) != tt.err:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.transaction && (e == nil || e.Transaction == nil || e.Transaction.Transaction.Address != tx.Address):
+			t.Errorf("%s: transaction was not parsed", tt.name)
+		case tt.confirmation && (e == nil || e.Confirmation == nil || e.Confirmation.MilestoneIndex != 12 || e.Confirmation.Address != Address(adr)):
+			t.Errorf("%s: confirmation was not parsed", tt.name)
+		case !tt.transaction && !tt.confirmation && e != nil:
+			t.Errorf("%s: unexpected event %v", tt.name, e)
+		}
+	}
+}
+
+func receiveEvent(t *testing.T, sub *Subscription) FeedEvent {
+	select {
+	case e, ok := <-sub.C:
+		if !ok {
+			t.Fatal("subscription was closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return FeedEvent{}
+}
+
+func TestSubscriber(t *testing.T) {
+	srv, err := NewFeedServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	tr, err := DialFeed(srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSubscriber(tr)
+
+	adr := Address(strings.Repeat("A", 81))
+	other := Address(strings.Repeat("B", 81))
+	bundle := Trytes(strings.Repeat("C", 81))
+
+	byAddress, err := s.SubscribeAddress(adr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byBundle, err := s.SubscribeBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1500000000, 0)
+	txOther := Transaction{Address: other, Bundle: EmptyHash, Timestamp: ts}
+	txAdr := Transaction{Address: adr, Bundle: bundle, Timestamp: ts, TrunkTransaction: EmptyHash, BranchTransaction: EmptyHash}
+	srv.Publish(TopicConfirmation, "malformed")
+	srv.PublishTransaction(&txOther)
+	srv.PublishTransaction(&txAdr)
+	srv.PublishConfirmation(7, &txAdr)
+
+	for _, sub := range []*Subscription{byAddress, byBundle} {
+		e := receiveEvent(t, sub)
+		if e.Transaction == nil || e.Transaction.Hash != txAdr.Hash() {
+			t.Errorf("expected the transaction of the subscription but got %v", e)
+		}
+
+		e = receiveEvent(t, sub)
+		if e.Confirmation == nil || e.Confirmation.Hash != txAdr.Hash() || e.Confirmation.MilestoneIndex != 7 {
+			t.Errorf("expected the confirmation of the subscription but got %v", e)
+		}
+	}
+
+	byBundle.Close()
+	if _, ok := <-byBundle.C; ok {
+		t.Error("closed subscription received an event")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-byAddress.C; ok {
+		t.Error("subscription is not closed with the subscriber")
+	}
+	if _, err := s.SubscribeAll(); err == nil {
+		t.Error("SubscribeAll() succeeded on a closed subscriber")
+	}
+}
+
+func TestFeedServerSlowClient(t *testing.T) {
+	srv, err := NewFeedServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	// a client which subscribes to everything and stops reading
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "subscribe \n")
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		payload := strings.Repeat("9", 16*1024)
+		for i := 0; i < 4*feedClientQueue; i++ {
+			srv.Publish(TopicTransaction, payload)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Publish() is stalled by a slow client")
+	}
+
+	srv.mu.Lock()
+	n := len(srv.clients)
+	srv.mu.Unlock()
+	if n != 0 {
+		t.Error("the slow client was not disconnected")
+	}
+}
@@ -0,0 +1,101 @@
+package giota
+
+import (
+	"errors"
+	"sync"
+)
+
+// SpongeFactory returns a new TritHasher of a hash function.
+type SpongeFactory func() TritHasher
+
+// HashScheme is the set of hash functions of a network. Transaction hashes,
+// bundle hashes, address checksums and PoW all use the current scheme, set
+// with SetHashScheme.
+//
+// Troika isn't implemented by the package. A network using it sets a scheme
+// whose factories return a TritHasher of Troika and whose CurlRounds is zero,
+// so that PoW, HashTransactions and ValidateNonces hash with that TritHasher
+// like KerlScheme.
+type HashScheme struct {
+	Name string
+
+	// Transaction hashes transactions, so PoW searches nonces of its hashes.
+	Transaction SpongeFactory
+
+	// Bundle hashes the essences of the transactions of bundles.
+	Bundle SpongeFactory
+
+	// Address hashes addresses, whose checksums are the end of their hashes.
+	Address SpongeFactory
+
+	// CurlRounds is the number of rounds of Transaction if it returns
+	// Curl-P hashers, which the PoW implementations search nonces of.
+	// Otherwise it is zero and nonces are searched in Go with Transaction.
+	CurlRounds int
+}
+
+// hash schemes of the package
+var (
+	// CurlPScheme is the scheme of IOTA: Curl-P-81 hashes transactions and
+	// Kerl bundles and addresses.
+	CurlPScheme = &HashScheme{
+		Name:        "curlp",
+		Transaction: func() TritHasher { return NewCurlHasher() },
+		Bundle:      func() TritHasher { return NewKerlHasher() },
+		Address:     func() TritHasher { return NewKerlHasher() },
+		CurlRounds:  CurlP81,
+	}
+
+	// CurlP27Scheme is CurlPScheme with Curl-P-27 hashing transactions.
+	CurlP27Scheme = &HashScheme{
+		Name:        "curlp27",
+		Transaction: func() TritHasher { return NewCurlPHasher(CurlP27) },
+		Bundle:      func() TritHasher { return NewKerlHasher() },
+		Address:     func() TritHasher { return NewKerlHasher() },
+		CurlRounds:  CurlP27,
+	}
+
+	// KerlScheme hashes everything with Kerl.
+	KerlScheme = &HashScheme{
+		Name:        "kerl",
+		Transaction: func() TritHasher { return NewKerlHasher() },
+		Bundle:      func() TritHasher { return NewKerlHasher() },
+		Address:     func() TritHasher { return NewKerlHasher() },
+	}
+)
+
+// ErrInvalidHashScheme is returned when setting a scheme without a hasher.
+var ErrInvalidHashScheme = errors.New("hash scheme misses a sponge factory")
+
+var hashScheme = struct {
+	sync.RWMutex
+	s *HashScheme
+}{s: CurlPScheme}
+
+// CurrentHashScheme returns the scheme used by the package, CurlPScheme
+// unless SetHashScheme was called.
+func CurrentHashScheme() *HashScheme {
+	hashScheme.RLock()
+	defer hashScheme.RUnlock()
+	return hashScheme.s
+}
+
+// SetHashScheme makes the package use the scheme s. It should be called
+// before hashing anything, since hashes of different schemes don't match.
+func SetHashScheme(s *HashScheme) error {
+	if s == nil || s.Transaction == nil || s.Bundle == nil || s.Address == nil {
+		return ErrInvalidHashScheme
+	}
+
+	hashScheme.Lock()
+	hashScheme.s = s
+	hashScheme.Unlock()
+	return nil
+}
+
+// hashTrits returns the hash of tr by a hasher of f.
+func hashTrits(f SpongeFactory, tr Trits) Trytes {
+	h := f()
+	h.Write(tr)
+	return h.Sum(nil).Trytes()
+}
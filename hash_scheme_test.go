@@ -0,0 +1,93 @@
+package giota
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashSchemeDefault(t *testing.T) {
+	if CurrentHashScheme() != CurlPScheme {
+		t.Fatalf("current scheme is %s", CurrentHashScheme().Name)
+	}
+
+	in := Trytes("EMIDYNHBWMBCXVDEFOFWINXTERALUKYYPPHKP9JJFGJEIUY9MUDVNFZHMMWZUYUSWAIOWEVTHNWMHANBH")
+	c := NewCurl()
+	c.Absorb(in)
+	if h, e := in.Hash(), c.Squeeze(); h != e {
+		t.Errorf("transaction hash is %s, want the Curl-P-81 hash %s", h, e)
+	}
+
+	k := NewKerl()
+	k.Absorb(in.Trits())
+	e, _ := k.Squeeze(HashSize)
+	if h := Address(in).Hash(); h != e.Trytes() {
+		t.Errorf("address hash is %s, want the Kerl hash %s", h, e.Trytes())
+	}
+}
+
+func TestSetHashScheme(t *testing.T) {
+	defer SetHashScheme(CurlPScheme)
+
+	if err := SetHashScheme(&HashScheme{Name: "empty"}); err != ErrInvalidHashScheme {
+		t.Errorf("SetHashScheme() of a scheme without hashers returned %v", err)
+	}
+
+	// Curl-P-27 for everything
+	curl27 := func() TritHasher { return NewCurlPHasher(CurlP27) }
+	s := &HashScheme{Name: "test", Transaction: curl27, Bundle: curl27, Address: curl27, CurlRounds: CurlP27}
+	if err := SetHashScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := testPowTransaction()
+	if h, e := tx.Hash(), hashTrits(curl27, tx.Trytes().Trits()); h != e {
+		t.Errorf("transaction hash is %s, want %s", h, e)
+	}
+	adr := Address(tx.Bundle)
+	if h, e := adr.Checksum(), hashTrits(curl27, Trytes(adr).Trits())[72:]; h != e {
+		t.Errorf("address checksum is %s, want %s", h, e)
+	}
+
+	bs := Bundle(testPowBundle(2))
+	buf := make(Trits, 2*972)
+	for i := range bs {
+		getTritsToHash(buf[i*972:], &bs[i], i, len(bs))
+	}
+	if h, e := bs.Hash(), hashTrits(curl27, buf); h != e {
+		t.Errorf("bundle hash is %s, want %s", h, e)
+	}
+}
+
+func TestHashSchemePow(t *testing.T) {
+	defer SetHashScheme(CurlPScheme)
+
+	for _, s := range []*HashScheme{CurlP27Scheme, KerlScheme} {
+		if err := SetHashScheme(s); err != nil {
+			t.Fatal(err)
+		}
+		e, err := NewPowEngine("PowGo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Rounds() != s.CurlRounds {
+			t.Errorf("%s: engine has %d rounds", s.Name, e.Rounds())
+		}
+
+		tx := testPowTransaction()
+		r, err := e.Search(context.Background(), tx.Trytes(), 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Nonce = r.Nonce
+		if !tx.HasValidNonce(7) {
+			t.Errorf("%s: invalid nonce, hash is %s", s.Name, tx.Hash())
+		}
+
+		trunk, branch := EmptyHash, Trytes("A")+EmptyHash[1:]
+		b := NewBundlePow(e, testPowBundle(2), trunk, branch, 7)
+		if err := b.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		checkPowBundle(t, b.Transactions(), trunk, branch, 7)
+	}
+}
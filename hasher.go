@@ -0,0 +1,168 @@
+package giota
+
+// TritHasher hashes a stream of trits. The trits written are absorbed by
+// chunks of HashSize trits and the trits of an incomplete chunk are buffered
+// until the next Write or Sum.
+type TritHasher interface {
+	// Write absorbs the trits in. It never returns an error for valid trits.
+	Write(in Trits) (int, error)
+
+	// Sum appends the hash of the trits written to b and returns it. It
+	// doesn't change the state of the hasher.
+	Sum(b Trits) Trits
+
+	// Reset resets the hasher to its initial state.
+	Reset()
+
+	// Clone returns a copy of the hasher, whose state is independent of
+	// the original one. Cloning after writing a common prefix saves hashing
+	// it again.
+	Clone() TritHasher
+
+	// Size returns the number of trits of the hash.
+	Size() int
+}
+
+var (
+	_ TritHasher = (*CurlHasher)(nil)
+	_ TritHasher = (*KerlHasher)(nil)
+)
+
+// chunkBuffer holds the trits of an incomplete chunk.
+type chunkBuffer struct {
+	buf [HashSize]int8
+	n   int
+}
+
+// write fills the chunk with in and calls full every time it is complete.
+// It returns an error of full.
+func (c *chunkBuffer) write(in Trits, full func(Trits) error) error {
+	for len(in) > 0 {
+		k := copy(c.buf[c.n:], in)
+		c.n += k
+		in = in[k:]
+		if c.n == HashSize {
+			c.n = 0
+			if err := full(c.buf[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CurlHasher is the streaming Curl. Its hash is Curl.Squeeze after
+// Curl.Absorb of all the trits written, including an incomplete last chunk.
+type CurlHasher struct {
+	curl  *Curl
+	chunk chunkBuffer
+}
+
+// NewCurlHasher returns a CurlHasher of Curl-P-81.
+func NewCurlHasher() *CurlHasher {
+	return &CurlHasher{curl: NewCurl()}
+}
+
+// NewCurlPHasher returns a CurlHasher of Curl-P with rounds rounds.
+func NewCurlPHasher(rounds int) *CurlHasher {
+	return &CurlHasher{curl: NewCurlP(rounds)}
+}
+
+// Write implements TritHasher.
+func (h *CurlHasher) Write(in Trits) (int, error) {
+	h.chunk.write(in, func(chunk Trits) error {
+		h.curl.absorbTrits(chunk)
+		return nil
+	})
+	return len(in), nil
+}
+
+// Sum implements TritHasher.
+func (h *CurlHasher) Sum(b Trits) Trits {
+	c := h.curl
+	if h.chunk.n > 0 {
+		c = c.clone()
+		c.absorbTrits(h.chunk.buf[:h.chunk.n])
+	}
+	return append(b, c.state[:HashSize]...)
+}
+
+// Reset implements TritHasher.
+func (h *CurlHasher) Reset() {
+	h.curl.Reset()
+	h.chunk.n = 0
+}
+
+// Clone implements TritHasher.
+func (h *CurlHasher) Clone() TritHasher {
+	return &CurlHasher{curl: h.curl.clone(), chunk: h.chunk}
+}
+
+// Size implements TritHasher.
+func (h *CurlHasher) Size() int {
+	return HashSize
+}
+
+// KerlHasher is the streaming Kerl. Its hash is the first Kerl.Squeeze after
+// Kerl.Absorb of all the trits written. An incomplete last chunk is padded
+// with 0 trits, where Kerl would fail.
+type KerlHasher struct {
+	k     keccak384
+	chunk chunkBuffer
+}
+
+// NewKerlHasher returns a KerlHasher.
+func NewKerlHasher() *KerlHasher {
+	return &KerlHasher{}
+}
+
+// Write implements TritHasher. It returns an error without absorbing
+// anything if a trit of in isn't -1, 0 or 1.
+func (h *KerlHasher) Write(in Trits) (int, error) {
+	if err := in.IsValid(); err != nil {
+		return 0, err
+	}
+	err := h.chunk.write(in, func(chunk Trits) error {
+		return h.absorb(&h.k, chunk)
+	})
+	return len(in), err
+}
+
+func (h *KerlHasher) absorb(k *keccak384, chunk Trits) error {
+	b, err := chunk.Bytes()
+	if err != nil {
+		return err
+	}
+	k.write(b)
+	return nil
+}
+
+// Sum implements TritHasher.
+func (h *KerlHasher) Sum(b Trits) Trits {
+	k := h.k
+	if h.chunk.n > 0 {
+		last := make(Trits, HashSize)
+		copy(last, h.chunk.buf[:h.chunk.n])
+		h.absorb(&k, last)
+	}
+
+	s := k.sum()
+	ts, _ := BytesToTrits(s[:])
+	return append(b, ts...)
+}
+
+// Reset implements TritHasher.
+func (h *KerlHasher) Reset() {
+	*h = KerlHasher{}
+}
+
+// Clone implements TritHasher.
+func (h *KerlHasher) Clone() TritHasher {
+	c := *h
+	return &c
+}
+
+// Size implements TritHasher.
+func (h *KerlHasher) Size() int {
+	return HashSize
+}
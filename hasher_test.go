@@ -0,0 +1,153 @@
+package giota
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeccak384(t *testing.T) {
+	for in, out := range map[string]string{
+		"":    "2c23146a63a29acf99e73b88f8c24eaa7dc60aa771780ccc006afbfa8fe2479b2dd2b21362337441ac12b515911957ff",
+		"abc": "f7df1165f033337be098e7d288ad6a2f74409d7a60b49c36642218de161b1f99f8c681e4afaf31a34db29fb763e3c28e",
+	} {
+		var k keccak384
+		k.write([]byte(in))
+		if h := fmt.Sprintf("%x", k.sum()); h != out {
+			t.Errorf("Keccak-384(%q) = %s, want %s", in, h, out)
+		}
+	}
+}
+
+type testHasherVector struct {
+	name   string
+	hasher func() TritHasher
+	in     Trytes
+	out    Trytes
+}
+
+// testHasherVectors returns test vectors of IOTA, the hashes being the first
+// squeeze.
+func testHasherVectors() []testHasherVector {
+	return []testHasherVector{
+		{
+			name:   "kerl",
+			hasher: func() TritHasher { return NewKerlHasher() },
+			in:     "EMIDYNHBWMBCXVDEFOFWINXTERALUKYYPPHKP9JJFGJEIUY9MUDVNFZHMMWZUYUSWAIOWEVTHNWMHANBH",
+			out:    "EJEAOOZYSAWFPZQESYDHZCGYNSTWXUMVJOVDWUNZJXDGWCLUFGIMZRMGCAZGKNPLBRLGUNYWKLJTYEAQX",
+		},
+		{
+			name:   "kerl squeeze",
+			hasher: func() TritHasher { return NewKerlHasher() },
+			in:     "9MIDYNHBWMBCXVDEFOFWINXTERALUKYYPPHKP9JJFGJEIUY9MUDVNFZHMMWZUYUSWAIOWEVTHNWMHANBH",
+			out:    "G9JYBOMPUXHYHKSNRNMMSSZCSHOFYOYNZRSZMAAYWDYEIMVVOGKPJBVBM9TDPULSFUNMTVXRKFIDOHUXX",
+		},
+		{
+			name:   "kerl 2 chunks",
+			hasher: func() TritHasher { return NewKerlHasher() },
+			in:     "G9JYBOMPUXHYHKSNRNMMSSZCSHOFYOYNZRSZMAAYWDYEIMVVOGKPJBVBM9TDPULSFUNMTVXRKFIDOHUXXVYDLFSZYZTWQYTE9SPYYWYTXJYQ9IFGYOLZXWZBKWZN9QOOTBQMWMUBLEWUEEASRHRTNIQWJQNDWRYLCA",
+			out:    "LUCKQVACOGBFYSPPVSSOXJEKNSQQRQKPZC9NXFSMQNRQCGGUL9OHVVKBDSKEQEBKXRNUJSRXYVHJTXBPD",
+		},
+		{
+			name:   "curl",
+			hasher: func() TritHasher { return NewCurlHasher() },
+			in:     testCurlIn,
+			out:    testCurlOut,
+		},
+	}
+}
+
+func TestTritHasher(t *testing.T) {
+	for _, v := range testHasherVectors() {
+		in := v.in.Trits()
+
+		// the same hash whatever the writes
+		for _, step := range []int{len(in), 1, 7, 100, HashSize + 1} {
+			h := v.hasher()
+			for i := 0; i < len(in); i += step {
+				end := i + step
+				if end > len(in) {
+					end = len(in)
+				}
+				if _, err := h.Write(in[i:end]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if out := h.Sum(nil).Trytes(); out != v.out {
+				t.Errorf("%s: writing by %d trits, hash is %s, want %s", v.name, step, out, v.out)
+			}
+			if out := h.Sum(nil).Trytes(); out != v.out {
+				t.Errorf("%s: Sum changed the state", v.name)
+			}
+		}
+	}
+}
+
+func TestTritHasherClone(t *testing.T) {
+	prefix := Trytes("EMIDYNHBWMBCXVDEFOFWINXTERALUKYYPPHKP9JJFGJEIUY9MUDVNFZHMMWZUYUSWAIOWEVTHNWMHANBH")
+	for _, newHasher := range []func() TritHasher{
+		func() TritHasher { return NewCurlHasher() },
+		func() TritHasher { return NewKerlHasher() },
+	} {
+		mid := newHasher()
+		mid.Write(prefix.Trits()[:HashSize-3])
+
+		sums := make(map[Trytes]bool)
+		for _, suffix := range []Trytes{"A", "B", "C"} {
+			h := mid.Clone()
+			h.Write(suffix.Trits())
+
+			whole := newHasher()
+			whole.Write(prefix.Trits()[:HashSize-3])
+			whole.Write(suffix.Trits())
+			if s := h.Sum(nil).Trytes(); s != whole.Sum(nil).Trytes() {
+				t.Errorf("%T: hash of the clone is %s, want %s", h, s, whole.Sum(nil).Trytes())
+			}
+			sums[h.Sum(nil).Trytes()] = true
+		}
+		if len(sums) != 3 {
+			t.Errorf("%T: clones share their state", mid)
+		}
+
+		mid.Reset()
+		if s, e := mid.Sum(nil).Trytes(), newHasher().Sum(nil).Trytes(); s != e {
+			t.Errorf("%T: hash after Reset is %s, want %s", mid, s, e)
+		}
+	}
+}
+
+func TestCurlHasherTransaction(t *testing.T) {
+	tx := testPowTransaction()
+	h := NewCurlHasher()
+	for _, tr := range splitTrits(tx.Trytes().Trits(), 1000) {
+		h.Write(tr)
+	}
+	if s := h.Sum(nil).Trytes(); s != tx.Hash() {
+		t.Errorf("hash is %s, want %s", s, tx.Hash())
+	}
+}
+
+func TestKerlHasherInvalidTrits(t *testing.T) {
+	in := Trytes("EMIDYNHBWMBCXVDEFOFWINXTERALUKYYPPHKP9JJFGJEIUY9MUDVNFZHMMWZUYUSWAIOWEVTHNWMHANBH").Trits()
+	h := NewKerlHasher()
+	h.Write(in[:HashSize-1])
+	if _, err := h.Write(append(in[HashSize-1:], 2)); err == nil {
+		t.Error("no error for invalid trits")
+	}
+
+	e := NewKerlHasher()
+	e.Write(in[:HashSize-1])
+	if s := h.Sum(nil).Trytes(); s != e.Sum(nil).Trytes() {
+		t.Error("invalid trits were absorbed")
+	}
+}
+
+// splitTrits splits tr in slices of size trits.
+func splitTrits(tr Trits, size int) []Trits {
+	var s []Trits
+	for len(tr) > size {
+		s = append(s, tr[:size])
+		tr = tr[size:]
+	}
+	return append(s, tr)
+}
@@ -0,0 +1,217 @@
+package giota
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// errors used by bundle retrieval
+var (
+	ErrNotTail            = errors.New("transaction is not a tail transaction")
+	ErrTransactionUnknown = errors.New("transaction is not found")
+)
+
+// BundleRecord is a bundle found in the tangle with all of its attachments.
+type BundleRecord struct {
+	// Hash is the bundle hash.
+	Hash Trytes
+
+	// Bundle is the first valid attachment of the bundle.
+	Bundle Bundle
+
+	// Tails are the tail transactions of all valid attachments, i.e. the
+	// original one and its reattachments.
+	Tails []Trytes
+
+	// Sent and Received are the transactions of the bundle spending from and
+	// sending to the addresses the record was searched for.
+	Sent     Bundle
+	Received Bundle
+}
+
+// chainBundle follows the TrunkTransaction links from the tail transaction tail
+// with get and returns the bundle in CurrentIndex order.
+func chainBundle(tail Trytes, get func(Trytes) (*Transaction, error)) (Bundle, error) {
+	tx, err := get(tail)
+	if err != nil {
+		return nil, err
+	}
+	if tx.CurrentIndex != 0 {
+		return nil, ErrNotTail
+	}
+
+	bundle := Bundle{*tx}
+	for tx.CurrentIndex < tx.LastIndex {
+		next, err := get(tx.TrunkTransaction)
+		if err != nil {
+			return nil, err
+		}
+
+		if next.Bundle != tx.Bundle || next.CurrentIndex != tx.CurrentIndex+1 || next.LastIndex != tx.LastIndex {
+			return nil, fmt.Errorf("transaction %s does not continue bundle %s", tx.TrunkTransaction, tx.Bundle)
+		}
+
+		bundle = append(bundle, *next)
+		tx = next
+	}
+	return bundle, nil
+}
+
+// GetBundle fetches the bundle of the tail transaction tail by following its
+// TrunkTransaction links and validates it.
+func GetBundle(api NodeAPI, tail Trytes) (Bundle, error) {
+	return getBundle(api, tail, Bundle.IsValid)
+}
+
+// getBundle is GetBundle validating the bundle with validate.
+func getBundle(api NodeAPI, tail Trytes, validate func(Bundle) error) (Bundle, error) {
+	bundle, err := chainBundle(tail, func(h Trytes) (*Transaction, error) {
+		resp, err := api.GetTrytes([]Trytes{h})
+		switch {
+		case err != nil:
+			return nil, err
+		case len(resp.Trytes) != 1 || resp.Trytes[0].Bundle == EmptyHash:
+			return nil, ErrTransactionUnknown
+		}
+		return &resp.Trytes[0], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// FindBundlesByAddress returns the valid bundles which have transactions
+// with adr, oldest first. Reattachments are grouped into the same BundleRecord.
+func FindBundlesByAddress(api NodeAPI, adr Address) ([]BundleRecord, error) {
+	return findBundles(api, []Address{adr}, Bundle.IsValid)
+}
+
+// GetAccountHistory returns the valid bundles of all used addresses of a
+// keyring, oldest first.
+func GetAccountHistory(api NodeAPI, k *Keyring) ([]BundleRecord, error) {
+	report, err := ScanAddresses(api, k, DefaultGapLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	used := report.Used()
+	if len(used) == 0 {
+		return nil, nil
+	}
+	return findBundles(api, used, Bundle.IsValid)
+}
+
+func getTransactions(api NodeAPI, ft *FindTransactionsRequest) ([]Transaction, error) {
+	resp, err := api.FindTransactions(ft)
+	if err != nil || len(resp.Hashes) == 0 {
+		return nil, err
+	}
+
+	txs, err := api.GetTrytes(resp.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	return txs.Trytes, nil
+}
+
+// findBundles returns the records of the bundles which have transactions with
+// adrs and are valid for validate.
+func findBundles(api NodeAPI, adrs []Address, validate func(Bundle) error) ([]BundleRecord, error) {
+	attachments, err := findAttachments(api, adrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []BundleRecord
+	index := make(map[Trytes]int)
+	for _, bundle := range attachments {
+		if validate(bundle) != nil {
+			// spam is ignored
+			continue
+		}
+
+		tail := bundle[0].Hash()
+		if i, ok := index[bundle[0].Bundle]; ok {
+			records[i].Tails = append(records[i].Tails, tail)
+			continue
+		}
+
+		r := BundleRecord{
+			Hash:   bundle[0].Bundle,
+			Bundle: bundle,
+			Tails:  []Trytes{tail},
+		}
+		for _, adr := range adrs {
+			sent, received := bundle.Categorize(adr)
+			r.Sent = append(r.Sent, sent...)
+			r.Received = append(r.Received, received...)
+		}
+
+		index[bundle[0].Bundle] = len(records)
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// findAttachments returns the attachments of the bundles which have
+// transactions with adrs, oldest first, without validating them. Broken
+// attachments are ignored.
+func findAttachments(api NodeAPI, adrs []Address) ([]Bundle, error) {
+	txs, err := getTransactions(api, &FindTransactionsRequest{Addresses: adrs})
+	if err != nil || len(txs) == 0 {
+		return nil, err
+	}
+
+	seen := make(map[Trytes]bool)
+	var hashes []Trytes
+	for _, tx := range txs {
+		if !seen[tx.Bundle] {
+			seen[tx.Bundle] = true
+			hashes = append(hashes, tx.Bundle)
+		}
+	}
+
+	// all transactions of the bundles, including those of other addresses
+	txs, err = getTransactions(api, &FindTransactionsRequest{Bundles: hashes})
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[Trytes]*Transaction, len(txs))
+	var tails []*Transaction
+	for i := range txs {
+		byHash[txs[i].Hash()] = &txs[i]
+		if txs[i].CurrentIndex == 0 {
+			tails = append(tails, &txs[i])
+		}
+	}
+
+	// oldest attachment first
+	sort.SliceStable(tails, func(i, j int) bool {
+		return tails[i].Timestamp.Before(tails[j].Timestamp)
+	})
+
+	get := func(h Trytes) (*Transaction, error) {
+		tx, ok := byHash[h]
+		if !ok {
+			return nil, ErrTransactionUnknown
+		}
+		return tx, nil
+	}
+
+	var bundles []Bundle
+	for _, tail := range tails {
+		bundle, err := chainBundle(tail.Hash(), get)
+		if err != nil {
+			continue
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
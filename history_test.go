@@ -0,0 +1,125 @@
+package giota
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	historySender   = Address(strings.Repeat("A", 81))
+	historyReceiver = Address(strings.Repeat("B", 81))
+)
+
+// historyBundle returns a transfer from historySender to historyReceiver
+// attached on branch.
+func historyBundle(bundle Trytes, branch Trytes, tag Trytes) []Transaction {
+	ts := time.Unix(1500000000, 0)
+	out := Transaction{
+		Address:           historyReceiver,
+		RangeProof:        Trytes(strings.Repeat("A", 6)),
+		Timestamp:         ts,
+		CurrentIndex:      1,
+		LastIndex:         1,
+		Bundle:            bundle,
+		TrunkTransaction:  branch,
+		BranchTransaction: branch,
+		Tag:               tag,
+	}
+	in := Transaction{
+		Address:           historySender,
+		RangeProof:        Trytes(strings.Repeat("9", 6)),
+		Timestamp:         ts,
+		CurrentIndex:      0,
+		LastIndex:         1,
+		Bundle:            bundle,
+		TrunkTransaction:  out.Hash(),
+		BranchTransaction: branch,
+		Tag:               tag,
+	}
+	return []Transaction{in, out}
+}
+
+// testBundleValidation only rejects bundles tagged INVALID, so that bundles
+// without proofs are used.
+func testBundleValidation(bs Bundle) error {
+	if bs[0].Tag == "INVALID" {
+		return errors.New("invalid bundle")
+	}
+	return nil
+}
+
+func historyAPI(bundles ...[]Transaction) *scanMockAPI {
+	api := &scanMockAPI{txs: make(map[Trytes]Transaction)}
+	for _, b := range bundles {
+		for _, tx := range b {
+			api.txs[tx.Hash()] = tx
+		}
+	}
+	return api
+}
+
+func TestGetBundle(t *testing.T) {
+	b1 := historyBundle(Trytes(strings.Repeat("C", 81)), EmptyHash, "")
+	invalid := historyBundle(Trytes(strings.Repeat("D", 81)), EmptyHash, "INVALID")
+	api := historyAPI(b1, invalid, b1[:1])
+
+	bundle, err := getBundle(api, b1[0].Hash(), testBundleValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle) != 2 || bundle[0].Hash() != b1[0].Hash() || bundle[1].Hash() != b1[1].Hash() {
+		t.Error("GetBundle() did not return the bundle in order")
+	}
+
+	if _, err := getBundle(api, b1[1].Hash(), testBundleValidation); err != ErrNotTail {
+		t.Errorf("GetBundle() expected ErrNotTail but got %v", err)
+	}
+	if _, err := getBundle(api, invalid[0].Hash(), testBundleValidation); err == nil {
+		t.Error("GetBundle() returned an invalid bundle")
+	}
+
+	broken := historyBundle(Trytes(strings.Repeat("E", 81)), EmptyHash, "")
+	api = historyAPI(broken[:1])
+	if _, err := getBundle(api, broken[0].Hash(), testBundleValidation); err != ErrTransactionUnknown {
+		t.Errorf("GetBundle() expected ErrTransactionUnknown but got %v", err)
+	}
+}
+
+func TestFindBundlesByAddress(t *testing.T) {
+	hash := Trytes(strings.Repeat("C", 81))
+	b1 := historyBundle(hash, EmptyHash, "")
+	reattached := historyBundle(hash, b1[0].Hash(), "")
+	invalid := historyBundle(Trytes(strings.Repeat("D", 81)), EmptyHash, "INVALID")
+	api := historyAPI(b1, reattached, invalid)
+
+	records, err := findBundles(api, []Address{historyReceiver}, testBundleValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("FindBundlesByAddress() returned %d bundles, expected 1", len(records))
+	}
+
+	r := records[0]
+	if r.Hash != hash || len(r.Tails) != 2 {
+		t.Errorf("reattachments were not grouped: %d tails", len(r.Tails))
+	}
+	if len(r.Received) != 1 || len(r.Sent) != 0 {
+		t.Errorf("bundle was not categorized for the receiver: %d sent, %d received", len(r.Sent), len(r.Received))
+	}
+
+	records, err = findBundles(api, []Address{historySender}, testBundleValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(records[0].Sent) != 1 || len(records[0].Received) != 0 {
+		t.Error("bundle was not categorized for the sender")
+	}
+
+	records, err = findBundles(api, []Address{EmptyAddress}, testBundleValidation)
+	if err != nil || len(records) != 0 {
+		t.Errorf("FindBundlesByAddress() = %v, %v for an unused address", records, err)
+	}
+}
@@ -0,0 +1,103 @@
+package giota
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccak384Size and keccak384Rate are the sizes in bytes of a Keccak-384
+// hash and of the blocks it absorbs.
+const (
+	keccak384Size = 48
+	keccak384Rate = 200 - 2*keccak384Size
+)
+
+var (
+	keccakRoundConstants = [24]uint64{
+		0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+		0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+		0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+		0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+		0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+		0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+	}
+	keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakLanes     = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+// keccak384 is Keccak-384 with the original padding of Keccak, which Kerl
+// uses. Unlike a hash.Hash its state is a value, so it is copied by
+// assignment.
+type keccak384 struct {
+	a   [25]uint64
+	buf [keccak384Rate]byte
+	n   int
+}
+
+func (k *keccak384) write(b []byte) {
+	for len(b) > 0 {
+		c := copy(k.buf[k.n:], b)
+		k.n += c
+		b = b[c:]
+		if k.n == keccak384Rate {
+			k.absorb()
+		}
+	}
+}
+
+func (k *keccak384) absorb() {
+	for i := 0; i < keccak384Rate/8; i++ {
+		k.a[i] ^= binary.LittleEndian.Uint64(k.buf[8*i:])
+	}
+	keccakF1600(&k.a)
+	k.n = 0
+}
+
+// sum returns the hash of the bytes written to k, leaving k unmodified.
+func (k keccak384) sum() [keccak384Size]byte {
+	for i := k.n; i < keccak384Rate; i++ {
+		k.buf[i] = 0
+	}
+	k.buf[k.n] ^= 0x01
+	k.buf[keccak384Rate-1] ^= 0x80
+	k.absorb()
+
+	var h [keccak384Size]byte
+	for i := 0; i < keccak384Size/8; i++ {
+		binary.LittleEndian.PutUint64(h[8*i:], k.a[i])
+	}
+	return h
+}
+
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	for r := 0; r < len(keccakRoundConstants); r++ {
+		// theta
+		for i := 0; i < 5; i++ {
+			c[i] = a[i] ^ a[i+5] ^ a[i+10] ^ a[i+15] ^ a[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			d := c[(i+4)%5] ^ bits.RotateLeft64(c[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				a[j+i] ^= d
+			}
+		}
+
+		// rho and pi
+		t := a[1]
+		for i, j := range keccakLanes {
+			a[j], t = bits.RotateLeft64(t, keccakRotations[i]), a[j]
+		}
+
+		// chi
+		for j := 0; j < 25; j += 5 {
+			copy(c[:], a[j:j+5])
+			for i := 0; i < 5; i++ {
+				a[j+i] ^= ^c[(i+1)%5] & c[(i+2)%5]
+			}
+		}
+
+		// iota
+		a[0] ^= keccakRoundConstants[r]
+	}
+}
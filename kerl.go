@@ -26,23 +26,18 @@ package giota
 
 import (
 	"fmt"
-	"hash"
-
-	keccak "github.com/tildeleb/hashland/keccakpg"
 )
 
-// Kerl ...
-// TODO: find out the difference between this anc Curl and document
+// Kerl is the sponge of Keccak-384 over trits: every chunk of HashSize trits
+// is absorbed as 48 bytes and every squeeze returns the trits of a Keccak
+// hash. KerlHasher is the streaming Kerl.
 type Kerl struct {
-	s hash.Hash
+	s keccak384
 }
 
 // NewKerl returns a new Kerl
 func NewKerl() *Kerl {
-	k := &Kerl{
-		s: keccak.New384(),
-	}
-	return k
+	return &Kerl{}
 }
 
 // Squeeze out `length` trits. Length has to be a multiple of TritHashLength.
@@ -54,18 +49,18 @@ func (k *Kerl) Squeeze(length int) (Trits, error) {
 
 	out := make(Trits, length)
 	for i := 1; i <= length/HashSize; i++ {
-		h := k.s.Sum(nil)
-		ts, err := BytesToTrits(h)
+		h := k.s.sum()
+		ts, err := BytesToTrits(h[:])
 		if err != nil {
 			return nil, err
 		}
 		//ts[HashSize-1] = 0
 		copy(out[HashSize*(i-1):HashSize*i], ts)
-		k.s.Reset()
+		k.s = keccak384{}
 		for i, e := range h {
 			h[i] = ^e
 		}
-		k.s.Write(h)
+		k.s.write(h[:])
 	}
 
 	return out, nil
@@ -84,7 +79,7 @@ func (k *Kerl) Absorb(in Trits) error {
 		if err != nil {
 			return err
 		}
-		k.s.Write(b)
+		k.s.write(b)
 	}
 
 	return nil
@@ -92,5 +87,5 @@ func (k *Kerl) Absorb(in Trits) error {
 
 // Reset the internal state of the Kerl sponge.
 func (k *Kerl) Reset() {
-	k.s.Reset()
+	k.s = keccak384{}
 }
@@ -0,0 +1,69 @@
+package giota
+
+import (
+	"github.com/NebulousLabs/hdkey"
+)
+
+// Keyring derives the keys and addresses of a seed. Unlike NewAddress and
+// NewSecKey, which share the master key of the last seed used, it keeps its
+// own master key, so keyrings of several seeds can be used side by side.
+type Keyring struct {
+	seed   Trytes
+	master *hdkey.HDKey
+}
+
+// NewKeyring returns the Keyring of seed.
+func NewKeyring(seed Trytes) (*Keyring, error) {
+	bytesSec, err := seed.Trits().Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := hdkey.NewMaster(bytesSec, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{seed: seed, master: master}, nil
+}
+
+// Seed returns the seed of the keyring.
+func (k *Keyring) Seed() Trytes {
+	return k.seed
+}
+
+// SecKey returns the child key at index like NewSecKey.
+func (k *Keyring) SecKey(index int) (*hdkey.HDKey, error) {
+	return k.master.Child(uint32(index))
+}
+
+// Address returns the address at index like NewAddress.
+func (k *Keyring) Address(index int) (Address, error) {
+	key, err := k.master.Child(uint32(index + 1))
+	if err != nil {
+		return "", err
+	}
+
+	return keyAddress(key)
+}
+
+// keyAddress returns the address of the public key of key.
+func keyAddress(key *hdkey.HDKey) (Address, error) {
+	t, err := publicKeyTrytes(key)
+	if err != nil {
+		return "", err
+	}
+	return t.ToAddress()
+}
+
+// Addresses returns n addresses starting at index start.
+func (k *Keyring) Addresses(start, n int) ([]Address, error) {
+	adrs := make([]Address, n)
+	for i := range adrs {
+		adr, err := k.Address(start + i)
+		if err != nil {
+			return nil, err
+		}
+		adrs[i] = adr
+	}
+	return adrs, nil
+}
@@ -0,0 +1,354 @@
+package giota
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FieldCodec is the encoding of a field of Transaction in trits.
+type FieldCodec int
+
+// codecs of the fields of Transaction
+const (
+	// TrytesCodec encodes Trytes as they are.
+	TrytesCodec FieldCodec = iota
+
+	// AddressCodec encodes an Address, which is checked when it is parsed.
+	AddressCodec
+
+	// IntCodec encodes an integer with Int2Trits.
+	IntCodec
+
+	// TimeCodec encodes a time.Time as Unix seconds with Int2Trits.
+	TimeCodec
+)
+
+// versions of the transaction layouts of the package
+const (
+	// LayoutVersionDefault is the layout of this package, with a Pedersen
+	// commitment and a range proof instead of a value.
+	LayoutVersionDefault = 0
+
+	// LayoutVersionIOTA is the layout of IRI.
+	LayoutVersionIOTA = 1
+)
+
+// layoutVersionField is the name of the field which marks the version of
+// a transaction, if its layout has one.
+const layoutVersionField = "Version"
+
+// versionTrinarySize is the size of the Version field of DefaultLayout, the
+// last trits of the obsolete tag, which finalizing a bundle doesn't reach.
+const versionTrinarySize = 9
+
+// errors of layouts
+var (
+	ErrUnknownLayout = errors.New("unknown transaction layout")
+	ErrInvalidLayout = errors.New("invalid transaction layout")
+)
+
+// LayoutField is a field of Transaction in a Layout.
+type LayoutField struct {
+	// Name is the name of the field of Transaction.
+	Name   string
+	Offset int
+	Size   int
+	Codec  FieldCodec
+}
+
+// Layout is the schema of the trits of transactions of a version. The trits
+// not in a field are 0.
+//
+// Layouts of the same size must have a Version field with IntCodec, which
+// marks the version of their transactions.
+type Layout struct {
+	Version int
+	Name    string
+	Size    int
+	Fields  []LayoutField
+}
+
+var (
+	layoutsMu sync.RWMutex
+	layouts   = make(map[int]*Layout)
+)
+
+func init() {
+	for _, l := range []*Layout{DefaultLayout(), IOTALayout()} {
+		if err := RegisterLayout(l); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// DefaultLayout returns the layout of this package, version
+// LayoutVersionDefault. The last trits of the obsolete tag are its Version
+// field, so that layouts of the same size can be registered.
+func DefaultLayout() *Layout {
+	return &Layout{
+		Version: LayoutVersionDefault,
+		Name:    "giota",
+		Size:    transactionTrinarySize,
+		Fields: []LayoutField{
+			{"SignatureMessageFragment", SignatureMessageFragmentTrinaryOffset, SignatureMessageFragmentTrinarySize, TrytesCodec},
+			{"Address", AddressTrinaryOffset, AddressTrinarySize, AddressCodec},
+			{"VectorP", ValueTrinaryOffset, ValueTrinarySize, TrytesCodec},
+			{"Value", BlindingTrinaryOffset, BlindingTrinarySize, TrytesCodec},
+			{"RangeProof", RangeProofTrinaryOffset, RangeProofTrinarySize, TrytesCodec},
+			{"ObsoleteTag", ObsoleteTagTrinaryOffset, ObsoleteTagTrinarySize - versionTrinarySize, TrytesCodec},
+			{layoutVersionField, ObsoleteTagTrinaryOffset + ObsoleteTagTrinarySize - versionTrinarySize, versionTrinarySize, IntCodec},
+			{"Timestamp", TimestampTrinaryOffset, TimestampTrinarySize, TimeCodec},
+			{"CurrentIndex", CurrentIndexTrinaryOffset, CurrentIndexTrinarySize, IntCodec},
+			{"LastIndex", LastIndexTrinaryOffset, LastIndexTrinarySize, IntCodec},
+			{"Bundle", BundleTrinaryOffset, BundleTrinarySize, TrytesCodec},
+			{"TrunkTransaction", TrunkTransactionTrinaryOffset, TrunkTransactionTrinarySize, TrytesCodec},
+			{"BranchTransaction", BranchTransactionTrinaryOffset, BranchTransactionTrinarySize, TrytesCodec},
+			{"Tag", TagTrinaryOffset, TagTrinarySize, TrytesCodec},
+			{"AttachmentTimestamp", AttachmentTimestampTrinaryOffset, AttachmentTimestampTrinarySize, TrytesCodec},
+			{"AttachmentTimestampLowerBound", AttachmentTimestampLowerBoundTrinaryOffset, AttachmentTimestampLowerBoundTrinarySize, TrytesCodec},
+			{"AttachmentTimestampUpperBound", AttachmentTimestampUpperBoundTrinaryOffset, AttachmentTimestampUpperBoundTrinarySize, TrytesCodec},
+			{"Nonce", NonceTrinaryOffset, NonceTrinarySize, TrytesCodec},
+		},
+	}
+}
+
+// IOTALayout returns the layout of IRI, version LayoutVersionIOTA.
+// The value is in the trits of Value.
+func IOTALayout() *Layout {
+	l := &Layout{Version: LayoutVersionIOTA, Name: "iota"}
+	for _, f := range []struct {
+		name  string
+		size  int
+		codec FieldCodec
+	}{
+		{"SignatureMessageFragment", 6561, TrytesCodec},
+		{"Address", 243, AddressCodec},
+		{"Value", 81, TrytesCodec},
+		{"ObsoleteTag", 81, TrytesCodec},
+		{"Timestamp", 27, TimeCodec},
+		{"CurrentIndex", 27, IntCodec},
+		{"LastIndex", 27, IntCodec},
+		{"Bundle", 243, TrytesCodec},
+		{"TrunkTransaction", 243, TrytesCodec},
+		{"BranchTransaction", 243, TrytesCodec},
+		{"Tag", 81, TrytesCodec},
+		{"AttachmentTimestamp", 27, TrytesCodec},
+		{"AttachmentTimestampLowerBound", 27, TrytesCodec},
+		{"AttachmentTimestampUpperBound", 27, TrytesCodec},
+		{"Nonce", 81, TrytesCodec},
+	} {
+		l.Fields = append(l.Fields, LayoutField{Name: f.name, Offset: l.Size, Size: f.size, Codec: f.codec})
+		l.Size += f.size
+	}
+	return l
+}
+
+// RegisterLayout makes transactions of the version of l parsed and
+// serialized with l, replacing the layout of the version if any.
+func RegisterLayout(l *Layout) error {
+	if err := l.check(); err != nil {
+		return err
+	}
+
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+
+	for v, o := range layouts {
+		if v != l.Version && o.Size == l.Size && (!o.marked() || !l.marked()) {
+			return fmt.Errorf("%s: the layouts of versions %d and %d have the same size and no %s field",
+				ErrInvalidLayout, o.Version, l.Version, layoutVersionField)
+		}
+	}
+	layouts[l.Version] = l
+	return nil
+}
+
+// LayoutOf returns the layout of the version.
+func LayoutOf(version int) (*Layout, error) {
+	layoutsMu.RLock()
+	defer layoutsMu.RUnlock()
+
+	l, ok := layouts[version]
+	if !ok {
+		return nil, ErrUnknownLayout
+	}
+	return l, nil
+}
+
+// layoutOfTrits returns the layout of the transaction tr.
+func layoutOfTrits(tr Trits) (*Layout, error) {
+	layoutsMu.RLock()
+	defer layoutsMu.RUnlock()
+
+	for _, l := range layouts {
+		if l.Size != len(tr) {
+			continue
+		}
+		if f, ok := l.field(layoutVersionField); ok && tr[f.Offset:f.Offset+f.Size].Int() != int64(l.Version) {
+			continue
+		}
+		return l, nil
+	}
+	return nil, ErrUnknownLayout
+}
+
+func isLayoutSize(size int) bool {
+	for _, s := range layoutSizes() {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutSizes returns the sizes of the layouts, sorted.
+func layoutSizes() []int {
+	layoutsMu.RLock()
+	defer layoutsMu.RUnlock()
+
+	seen := make(map[int]bool)
+	var sizes []int
+	for _, l := range layouts {
+		if !seen[l.Size] {
+			seen[l.Size] = true
+			sizes = append(sizes, l.Size)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+func (l *Layout) field(name string) (LayoutField, bool) {
+	for _, f := range l.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return LayoutField{}, false
+}
+
+func (l *Layout) marked() bool {
+	f, ok := l.field(layoutVersionField)
+	return ok && f.Codec == IntCodec
+}
+
+// chainOffset returns the offset of the first chunk absorbed by Curl which
+// changes when a transaction is chained and attached.
+func (l *Layout) chainOffset() int {
+	f, _ := l.field("TrunkTransaction")
+	return f.Offset / HashSize * HashSize
+}
+
+// check checks that the fields of l are fields of Transaction of the kinds of
+// their codecs, that they don't overlap and that the nonce ends l.
+func (l *Layout) check() error {
+	if l.Size <= 0 || l.Size%3 != 0 {
+		return fmt.Errorf("%s: size %d is not a positive multiple of 3", ErrInvalidLayout, l.Size)
+	}
+
+	fields := make([]LayoutField, len(l.Fields))
+	copy(fields, l.Fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Offset < fields[j].Offset })
+
+	typ := reflect.TypeOf(Transaction{})
+	end := 0
+	for _, f := range fields {
+		if f.Offset < end || f.Size <= 0 || f.Offset+f.Size > l.Size {
+			return fmt.Errorf("%s: field %s is out of place", ErrInvalidLayout, f.Name)
+		}
+		end = f.Offset + f.Size
+
+		sf, ok := typ.FieldByName(f.Name)
+		if !ok || !f.Codec.accepts(sf.Type) {
+			return fmt.Errorf("%s: field %s can't be encoded with codec %d", ErrInvalidLayout, f.Name, f.Codec)
+		}
+	}
+
+	for _, name := range []string{"TrunkTransaction", "BranchTransaction"} {
+		if f, ok := l.field(name); !ok || f.Size != HashSize {
+			return fmt.Errorf("%s: no field %s of %d trits", ErrInvalidLayout, name, HashSize)
+		}
+	}
+	if f, ok := l.field("Nonce"); !ok || f.Size != NonceTrinarySize || f.Offset != l.Size-NonceTrinarySize {
+		return fmt.Errorf("%s: the last %d trits are not the nonce", ErrInvalidLayout, NonceTrinarySize)
+	}
+	return nil
+}
+
+var (
+	trytesType  = reflect.TypeOf(Trytes(""))
+	addressType = reflect.TypeOf(Address(""))
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+func (c FieldCodec) accepts(t reflect.Type) bool {
+	switch c {
+	case TrytesCodec:
+		return t == trytesType
+	case AddressCodec:
+		return t == addressType
+	case IntCodec:
+		return t.Kind() == reflect.Int || t.Kind() == reflect.Int64
+	case TimeCodec:
+		return t == timeType
+	}
+	return false
+}
+
+// Parse returns the transaction of the trits tr in layout l.
+func (l *Layout) Parse(tr Trits) (*Transaction, error) {
+	if len(tr) != l.Size {
+		return nil, errors.New("invalid trits counts in transaction")
+	}
+
+	t := &Transaction{}
+	v := reflect.ValueOf(t).Elem()
+	for _, f := range l.Fields {
+		ft := tr[f.Offset : f.Offset+f.Size]
+		fv := v.FieldByName(f.Name)
+		switch f.Codec {
+		case TrytesCodec:
+			fv.SetString(string(ft.Trytes()))
+		case AddressCodec:
+			a, err := ft.Trytes().ToAddress()
+			if err != nil {
+				return nil, err
+			}
+			fv.SetString(string(a))
+		case IntCodec:
+			fv.SetInt(ft.Int())
+		case TimeCodec:
+			fv.Set(reflect.ValueOf(time.Unix(ft.Int(), 0)))
+		}
+	}
+
+	if f, ok := l.field(layoutVersionField); ok && tr[f.Offset:f.Offset+f.Size].Int() != int64(l.Version) {
+		return nil, ErrUnknownLayout
+	}
+	t.Version = l.Version
+	return t, nil
+}
+
+// Trits returns the trits of t in layout l. Fields longer than their size are
+// cut.
+func (l *Layout) Trits(t *Transaction) Trits {
+	tr := make(Trits, l.Size)
+	v := reflect.ValueOf(t).Elem()
+	for _, f := range l.Fields {
+		ft := tr[f.Offset : f.Offset+f.Size]
+		fv := v.FieldByName(f.Name)
+		switch f.Codec {
+		case TrytesCodec, AddressCodec:
+			copy(ft, Trytes(fv.String()).Trits())
+		case IntCodec:
+			copy(ft, Int2Trits(fv.Int(), f.Size))
+		case TimeCodec:
+			copy(ft, Int2Trits(fv.Interface().(time.Time).Unix(), f.Size))
+		}
+	}
+	return tr
+}
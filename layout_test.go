@@ -0,0 +1,157 @@
+package giota
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testIOTATransaction() Transaction {
+	return Transaction{
+		Address:           Address(strings.Repeat("A", 81)),
+		Value:             Int2Trits(-42, 81).Trytes(),
+		Timestamp:         time.Unix(1500000000, 0),
+		CurrentIndex:      1,
+		LastIndex:         2,
+		Bundle:            Trytes(strings.Repeat("B", 81)),
+		TrunkTransaction:  EmptyHash,
+		BranchTransaction: EmptyHash,
+		Tag:               "IOTA",
+		Nonce:             "NONCE",
+		Version:           LayoutVersionIOTA,
+	}
+}
+
+func TestIOTALayout(t *testing.T) {
+	tx := testIOTATransaction()
+	trytes := tx.Trytes()
+	if len(trytes) != 2673 {
+		t.Fatalf("IOTA transaction has %d trytes, want 2673", len(trytes))
+	}
+
+	got, err := NewTransaction(trytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != LayoutVersionIOTA || got.Trytes() != trytes || got.Value.Trits().Int() != -42 {
+		t.Errorf("IOTA transaction was not parsed: %+v", got)
+	}
+
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bin Transaction
+	if err = bin.UnmarshalBinary(b); err != nil || bin.Trytes() != trytes {
+		t.Errorf("binary IOTA transaction was not parsed: %v", err)
+	}
+
+	def := testPowTransaction()
+	hs := HashTransactions([]Transaction{tx, def, tx})
+	if hs[0] != tx.Hash() || hs[1] != def.Hash() || hs[2] != tx.Hash() {
+		t.Error("HashTransactions() of mixed versions is wrong")
+	}
+}
+
+func TestRegisterLayout(t *testing.T) {
+	defer func() {
+		layoutsMu.Lock()
+		delete(layouts, 7)
+		delete(layouts, 8)
+		layoutsMu.Unlock()
+	}()
+
+	// an IOTA layout whose obsolete tag marks the version
+	marked := func(v int) *Layout {
+		l := IOTALayout()
+		l.Version = v
+		l.Fields[3] = LayoutField{Name: layoutVersionField, Offset: l.Fields[3].Offset, Size: 81, Codec: IntCodec}
+		return l
+	}
+
+	if err := RegisterLayout(marked(7)); err == nil {
+		t.Error("a layout of the size of an unmarked one was registered")
+	}
+
+	l := IOTALayout()
+	l.Version = 7
+	l.Fields = l.Fields[:len(l.Fields)-1]
+	if err := RegisterLayout(l); err == nil {
+		t.Error("a layout without a nonce was registered")
+	}
+
+	l = marked(7)
+	l.Size += 243
+	l.Fields[len(l.Fields)-1].Offset += 243
+	if err := RegisterLayout(l); err != nil {
+		t.Fatal(err)
+	}
+	l8 := marked(8)
+	l8.Size, l8.Fields = l.Size, l.Fields
+	if err := RegisterLayout(l8); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []int{7, 8} {
+		tx := testIOTATransaction()
+		tx.Version = v
+		got, err := NewTransaction(tx.Trytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != v || got.Trytes() != tx.Trytes() {
+			t.Errorf("transaction of version %d was parsed as version %d", v, got.Version)
+		}
+	}
+
+	tx := testIOTATransaction()
+	tx.Version = 9
+	if tx.Trytes() != "" {
+		t.Error("a transaction of an unknown version has trytes")
+	}
+}
+
+func TestDefaultLayoutVersion(t *testing.T) {
+	defer func() {
+		layoutsMu.Lock()
+		delete(layouts, 7)
+		layoutsMu.Unlock()
+	}()
+
+	l := DefaultLayout()
+	l.Version, l.Name = 7, "giota7"
+	if err := RegisterLayout(l); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []int{LayoutVersionDefault, 7} {
+		tx := testPowTransaction()
+		tx.Version = v
+		trytes := tx.Trytes()
+		if len(trytes) != transactionTrinarySize/3 {
+			t.Fatalf("transaction of version %d has %d trytes", v, len(trytes))
+		}
+
+		got, err := NewTransaction(trytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != v || got.Trytes() != trytes {
+			t.Errorf("transaction of version %d was parsed as version %d", v, got.Version)
+		}
+	}
+}
+
+func TestMarshalUnknownLayout(t *testing.T) {
+	tx := &Transaction{Version: 99}
+	if b, err := tx.MarshalBinary(); err != ErrUnknownLayout {
+		t.Errorf("MarshalBinary() = %v, %v", b, err)
+	}
+	if b, err := tx.MarshalJSON(); err != ErrUnknownLayout {
+		t.Errorf("MarshalJSON() = %s, %v", b, err)
+	}
+	if _, err := json.Marshal([]*Transaction{tx}); err == nil {
+		t.Error("json.Marshal() of a transaction of an unknown layout succeeded")
+	}
+}
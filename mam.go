@@ -0,0 +1,328 @@
+package giota
+
+import (
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/hdkey/schnorr"
+)
+
+// ChannelMode is the visibility of the messages of a channel.
+type ChannelMode int
+
+// modes of channels
+const (
+	// ChannelPublic messages are in the clear at the address of their root,
+	// so anyone who knows a root reads the channel from there.
+	ChannelPublic ChannelMode = iota
+
+	// ChannelPrivate messages are encrypted with their root at the hash of
+	// their root, so only those who know a root find and read them.
+	ChannelPrivate
+
+	// ChannelRestricted messages are encrypted with their root and a side
+	// key at the hash of both, so readers need the side key too.
+	ChannelRestricted
+)
+
+// A channel message is a zero-value bundle sent to the channel address of
+// its root. Its fragments hold the root of the next message, the Schnorr
+// signature of the message by the key of its root and the encoded message,
+// sealed in the private and restricted modes.
+const (
+	channelSignatureSize = 2 * 64
+	channelKeyLabel      = "giota channel key"
+)
+
+// errors of channels
+var (
+	ErrInvalidChannelMode     = errors.New("invalid channel mode")
+	ErrChannelSideKey         = errors.New("restricted channels need a side key in trytes")
+	ErrChannelMessageNotFound = errors.New("channel message not found")
+	ErrInvalidChannelMessage  = errors.New("invalid channel message")
+)
+
+// ChannelMessage is a message read from a channel.
+type ChannelMessage struct {
+	// Root is the root of the message and NextRoot the root of the next one.
+	Root     Address
+	NextRoot Address
+
+	Message *Message
+
+	// Bundle is the bundle of the message.
+	Bundle Bundle
+}
+
+// Channel publishes an ordered stream of messages. Every message is signed
+// with the key of its index in a keyring and links to the root of the next
+// one, the root of a message being the address of its key.
+type Channel struct {
+	// Tag is the tag of the bundles of the messages.
+	Tag Trytes
+
+	keyring *Keyring
+	mode    ChannelMode
+	sideKey Trytes
+	index   int
+}
+
+// NewChannel returns the channel whose next message is signed with the key
+// of k at index. sideKey is only used by restricted channels.
+func NewChannel(k *Keyring, index int, mode ChannelMode, sideKey Trytes) (*Channel, error) {
+	if err := checkChannelMode(mode, sideKey); err != nil {
+		return nil, err
+	}
+	return &Channel{keyring: k, mode: mode, sideKey: sideKey, index: index}, nil
+}
+
+func checkChannelMode(mode ChannelMode, sideKey Trytes) error {
+	switch mode {
+	case ChannelPublic, ChannelPrivate:
+		return nil
+	case ChannelRestricted:
+		if sideKey == "" || sideKey.IsValid() != nil {
+			return ErrChannelSideKey
+		}
+		return nil
+	}
+	return ErrInvalidChannelMode
+}
+
+// Index returns the index of the key of the next message.
+func (c *Channel) Index() int {
+	return c.index
+}
+
+// Root returns the root of the next message. Readers follow the channel from
+// the root of its first message.
+func (c *Channel) Root() (Address, error) {
+	return c.root(c.index)
+}
+
+func (c *Channel) root(index int) (Address, error) {
+	key, err := c.keyring.SecKey(index)
+	if err != nil {
+		return "", err
+	}
+	return keyAddress(key)
+}
+
+// Create returns the finalized bundle of the next message m, which is
+// ready to be sent, and moves the channel to the following message.
+func (c *Channel) Create(m *Message) (Bundle, error) {
+	root, err := c.root(c.index)
+	if err != nil {
+		return nil, err
+	}
+	next, err := c.root(c.index + 1)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := m.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.keyring.SecKey(c.index)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := key.SecretKey()
+	if err != nil {
+		return nil, err
+	}
+	hash := channelSignedHash(root, next, payload)
+	sig, err := schnorr.Sign(sk, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	adr := channelAddress(root, c.mode, c.sideKey)
+	packet := Trytes(next) + bytesToTrytes(sig[:]) + payload
+	if c.mode != ChannelPublic {
+		sealed, err := SealMessage(channelSecret(root, c.sideKey), Trytes(root), 0, adr, packet)
+		if err != nil {
+			return nil, err
+		}
+		if packet, err = sealed.Encode(); err != nil {
+			return nil, err
+		}
+	}
+
+	tag := c.Tag
+	if tag == "" {
+		tag = EmptyHash[:27]
+	}
+	frags := splitFragments(packet)
+	now := time.Now()
+	bs := make(Bundle, len(frags))
+	for i := range bs {
+		bs[i] = Transaction{
+			SignatureMessageFragment:      emptySig,
+			Address:                       adr,
+			VectorP:                       pad("", ValueTrinarySize/3),
+			Value:                         pad("", BlindingTrinarySize/3),
+			RangeProof:                    pad("", RangeProofTrinarySize/3),
+			ObsoleteTag:                   pad(tag, TagTrinarySize/3),
+			Timestamp:                     now,
+			Bundle:                        EmptyHash,
+			TrunkTransaction:              EmptyHash,
+			BranchTransaction:             EmptyHash,
+			Tag:                           pad(tag, TagTrinarySize/3),
+			AttachmentTimestampLowerBound: EmptyHash,
+			AttachmentTimestampUpperBound: EmptyHash,
+			Nonce:                         EmptyHash,
+		}
+	}
+	bs.Finalize(frags)
+
+	c.index++
+	return bs, nil
+}
+
+// Publish creates the bundle of the next message m and sends it. If sending
+// fails, the bundle is returned with the error to be sent again with
+// SendTrytes, the channel being at the following message already.
+func (c *Channel) Publish(api NodeAPI, m *Message, mwm int64, pow PowFunc) (Bundle, error) {
+	bs, err := c.Create(m)
+	if err != nil {
+		return nil, err
+	}
+	return bs, SendTrytes(api, Depth, []Transaction(bs), mwm, pow)
+}
+
+// ChannelReader follows a channel from a root.
+type ChannelReader struct {
+	api     NodeAPI
+	mode    ChannelMode
+	sideKey Trytes
+	root    Address
+}
+
+// NewChannelReader returns a reader of the channel from the message of root.
+// sideKey is only used by restricted channels.
+func NewChannelReader(api NodeAPI, root Address, mode ChannelMode, sideKey Trytes) (*ChannelReader, error) {
+	if err := checkChannelMode(mode, sideKey); err != nil {
+		return nil, err
+	}
+	return &ChannelReader{api: api, mode: mode, sideKey: sideKey, root: root}, nil
+}
+
+// Root returns the root of the next message to read.
+func (r *ChannelReader) Root() Address {
+	return r.root
+}
+
+// Next returns the message of the root of r and moves r to the next one.
+// It returns ErrChannelMessageNotFound if the message isn't published yet.
+// Bundles sent to the channel address which aren't signed by the key of
+// the root are ignored.
+func (r *ChannelReader) Next() (*ChannelMessage, error) {
+	adr := channelAddress(r.root, r.mode, r.sideKey)
+	bundles, err := findAttachments(r.api, []Address{adr})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bs := range bundles {
+		m, err := readChannelMessage(bs, r.root, adr, r.mode, r.sideKey)
+		if err != nil {
+			continue
+		}
+		r.root = m.NextRoot
+		return m, nil
+	}
+	return nil, ErrChannelMessageNotFound
+}
+
+// ReadAll returns the messages from the root of r to the last one published.
+func (r *ChannelReader) ReadAll() ([]*ChannelMessage, error) {
+	var msgs []*ChannelMessage
+	for {
+		m, err := r.Next()
+		switch {
+		case err == ErrChannelMessageNotFound:
+			return msgs, nil
+		case err != nil:
+			return msgs, err
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// readChannelMessage returns the message of root in the bundle bs sent to
+// the channel address adr, checking its signature.
+func readChannelMessage(bs Bundle, root, adr Address, mode ChannelMode, sideKey Trytes) (*ChannelMessage, error) {
+	var packet Trytes
+	for i := range bs {
+		if bs[i].Address == adr {
+			packet += bs[i].SignatureMessageFragment
+		}
+	}
+
+	if mode != ChannelPublic {
+		sealed, err := DecodeMessage(packet)
+		if err != nil {
+			return nil, err
+		}
+		if packet, err = OpenMessage(channelSecret(root, sideKey), Trytes(root), 0, adr, sealed); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(packet) < len(root)+channelSignatureSize {
+		return nil, ErrInvalidChannelMessage
+	}
+	next, err := packet[:len(root)].ToAddress()
+	if err != nil {
+		return nil, err
+	}
+	b, ok := trytesToBytes(packet[len(root) : len(root)+channelSignatureSize])
+	if !ok {
+		return nil, ErrInvalidChannelMessage
+	}
+	payload := packet[len(root)+channelSignatureSize:]
+	m, err := DecodeMessage(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := root.DecodePubKey()
+	if err != nil {
+		return nil, err
+	}
+	sig := new(schnorr.Signature)
+	copy(sig[:], b)
+	hash := channelSignedHash(root, next, payload[:messageHeaderSize+2*len(m.Data)])
+	if err := schnorr.Verify(sig, pub, hash[:]); err != nil {
+		return nil, ErrInvalidChannelMessage
+	}
+	return &ChannelMessage{Root: root, NextRoot: next, Message: m, Bundle: bs}, nil
+}
+
+// channelSignedHash returns the hash signed by the key of root.
+func channelSignedHash(root, next Address, payload Trytes) [sha256.Size]byte {
+	return sha256.Sum256([]byte(string(root) + string(next) + string(payload)))
+}
+
+// channelAddress returns the address of the message of root.
+func channelAddress(root Address, mode ChannelMode, sideKey Trytes) Address {
+	switch mode {
+	case ChannelPrivate:
+		return Address(root.Hash())
+	case ChannelRestricted:
+		k := NewKerl()
+		k.Absorb(Trytes(root).Trits())
+		k.Absorb(pad(sideKey, (len(sideKey)+80)/81*81).Trits())
+		h, _ := k.Squeeze(HashSize)
+		return Address(h.Trytes())
+	}
+	return root
+}
+
+// channelSecret returns the secret of the messages of root.
+func channelSecret(root Address, sideKey Trytes) []byte {
+	return []byte(channelKeyLabel + string(root) + string(sideKey))
+}
@@ -0,0 +1,136 @@
+package giota
+
+import (
+	"fmt"
+	"testing"
+)
+
+// attachLocally chains the transactions of bs like attachToTangle without
+// PoW.
+func attachLocally(bs Bundle) []Transaction {
+	txs := make([]Transaction, len(bs))
+	copy(txs, bs)
+	prev := EmptyHash
+	for i := len(txs) - 1; i >= 0; i-- {
+		txs[i].TrunkTransaction = prev
+		txs[i].BranchTransaction = EmptyHash
+		prev = txs[i].Hash()
+	}
+	return txs
+}
+
+func TestChannel(t *testing.T) {
+	k, err := NewKeyring(testMessageSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []ChannelMode{ChannelPublic, ChannelPrivate, ChannelRestricted} {
+		sideKey := Trytes("")
+		if mode == ChannelRestricted {
+			sideKey = "SIDEKEY"
+		}
+
+		c, err := NewChannel(k, 5, mode, sideKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := c.Root()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var bundles [][]Transaction
+		for i := 0; i < 3; i++ {
+			bs, err := c.Create(NewTextMessage(fmt.Sprintf("reading %d", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bundles = append(bundles, attachLocally(bs))
+		}
+		if c.Index() != 8 {
+			t.Errorf("mode %d: channel is at index %d after 3 messages", mode, c.Index())
+		}
+
+		r, err := NewChannelReader(historyAPI(bundles...), root, mode, sideKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs, err := r.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 3 {
+			t.Fatalf("mode %d: read %d messages, want 3", mode, len(msgs))
+		}
+		for i, m := range msgs {
+			if s, _ := m.Message.Text(); s != fmt.Sprintf("reading %d", i) {
+				t.Errorf("mode %d: message %d is %q", mode, i, s)
+			}
+		}
+		if next, _ := c.Root(); r.Root() != next || msgs[2].NextRoot != next {
+			t.Errorf("mode %d: reader stopped at %s, want %s", mode, r.Root(), next)
+		}
+		if _, err := r.Next(); err != ErrChannelMessageNotFound {
+			t.Errorf("mode %d: reading past the last message returned %v", mode, err)
+		}
+	}
+}
+
+func TestChannelRestricted(t *testing.T) {
+	k, err := NewKeyring(testMessageSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewChannel(k, 0, ChannelRestricted, ""); err != ErrChannelSideKey {
+		t.Errorf("restricted channel without side key returned %v", err)
+	}
+	if _, err := NewChannel(k, 0, ChannelMode(7), ""); err != ErrInvalidChannelMode {
+		t.Errorf("unknown mode returned %v", err)
+	}
+
+	c, err := NewChannel(k, 0, ChannelRestricted, "SIDEKEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := c.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := c.Create(NewTextMessage("secret reading"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := attachLocally(bs)
+
+	// tampered copy of the message at the same address
+	bad := make(Bundle, len(bs))
+	copy(bad, bs)
+	bad[0].SignatureMessageFragment = "A" + bad[0].SignatureMessageFragment[1:]
+	bad[0].Bundle = pad("A", 81)
+
+	for _, tc := range []struct {
+		sideKey Trytes
+		txs     [][]Transaction
+		found   bool
+	}{
+		{"SIDEKEY", [][]Transaction{txs}, true},
+		{"OTHERKEY", [][]Transaction{txs}, false},
+		{"SIDEKEY", [][]Transaction{attachLocally(bad)}, false},
+		{"SIDEKEY", [][]Transaction{attachLocally(bad), txs}, true},
+	} {
+		r, err := NewChannelReader(historyAPI(tc.txs...), root, ChannelRestricted, tc.sideKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := r.Next()
+		switch {
+		case tc.found && err != nil:
+			t.Errorf("side key %s: %v", tc.sideKey, err)
+		case tc.found && m.Root != root:
+			t.Errorf("side key %s: message of root %s", tc.sideKey, m.Root)
+		case !tc.found && err != ErrChannelMessageNotFound:
+			t.Errorf("side key %s: reading returned %v", tc.sideKey, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package giota
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// MessageType is the content type of a Message.
+type MessageType int
+
+// content types of messages
+const (
+	// MessageTrytes is a message without a header, whose Data are its trytes
+	// without the trailing 9s. It is only returned by Bundle.Messages.
+	MessageTrytes MessageType = iota
+
+	// MessageText is UTF-8 text.
+	MessageText
+
+	// MessageBytes is raw bytes.
+	MessageBytes
+
+	// MessageJSON is a JSON value.
+	MessageJSON
+
+	// MessageEncrypted is a message sealed with SealMessage.
+	MessageEncrypted
+)
+
+// A message starts with a header of messageMagic, the content type in a
+// tryte and the length of the data in bytes in messageLengthSize trytes.
+// Every byte of the data is then encoded in 2 trytes like AsciiToTrytes.
+const (
+	messageMagic      Trytes = "MSG"
+	messageLengthSize        = 5
+	messageHeaderSize        = len(messageMagic) + 1 + messageLengthSize
+)
+
+// MaxMessageLength is the largest number of bytes in a Message.
+const MaxMessageLength = (27*27*27*27*27 - 1) / 2
+
+// errors of messages
+var (
+	ErrInvalidMessage     = errors.New("invalid message")
+	ErrMessageTooLong     = errors.New("message is too long")
+	ErrInvalidMessageType = errors.New("invalid message type")
+)
+
+// valid returns true if messages of type t can be encoded.
+func (t MessageType) valid() bool {
+	return t >= MessageText && t <= MessageEncrypted
+}
+
+// Message is a typed payload sent to an output of a transfer.
+type Message struct {
+	Type MessageType
+	Data []byte
+}
+
+// NewTextMessage returns the message of the text s.
+func NewTextMessage(s string) *Message {
+	return &Message{Type: MessageText, Data: []byte(s)}
+}
+
+// NewBytesMessage returns the message of the bytes b.
+func NewBytesMessage(b []byte) *Message {
+	return &Message{Type: MessageBytes, Data: b}
+}
+
+// NewJSONMessage returns the message of v marshaled to JSON.
+func NewJSONMessage(v interface{}) (*Message, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: MessageJSON, Data: b}, nil
+}
+
+// Text returns the data of a text message.
+func (m *Message) Text() (string, error) {
+	if m.Type != MessageText {
+		return "", ErrInvalidMessageType
+	}
+	return string(m.Data), nil
+}
+
+// JSON unmarshals the data of a JSON message into v.
+func (m *Message) JSON(v interface{}) error {
+	if m.Type != MessageJSON {
+		return ErrInvalidMessageType
+	}
+	return json.Unmarshal(m.Data, v)
+}
+
+// Encode returns the header and the data of m in trytes, to be used as the
+// message of a Transfer.
+func (m *Message) Encode() (Trytes, error) {
+	switch {
+	case m.Type == MessageText && !utf8.Valid(m.Data):
+		return "", ErrInvalidMessage
+	case m.Type == MessageJSON && !json.Valid(m.Data):
+		return "", ErrInvalidMessage
+	case !m.Type.valid():
+		return "", ErrInvalidMessageType
+	case len(m.Data) > MaxMessageLength:
+		return "", ErrMessageTooLong
+	}
+
+	return messageMagic +
+		Int2Trits(int64(m.Type), 3).Trytes() +
+		Int2Trits(int64(len(m.Data)), 3*messageLengthSize).Trytes() +
+		bytesToTrytes(m.Data), nil
+}
+
+// DecodeMessage returns the message encoded in t by Message.Encode. The
+// trytes after the data, like the padding of fragments, are ignored.
+func DecodeMessage(t Trytes) (*Message, error) {
+	if len(t) < messageHeaderSize || t[:len(messageMagic)] != messageMagic {
+		return nil, ErrInvalidMessage
+	}
+
+	h := t[len(messageMagic):messageHeaderSize]
+	m := &Message{Type: MessageType(h[:1].Trits().Int())}
+	n := int(h[1:].Trits().Int())
+	switch {
+	case !m.Type.valid():
+		return nil, ErrInvalidMessageType
+	case n < 0 || len(t) < messageHeaderSize+2*n:
+		return nil, ErrInvalidMessage
+	}
+
+	var ok bool
+	m.Data, ok = trytesToBytes(t[messageHeaderSize : messageHeaderSize+2*n])
+	if !ok {
+		return nil, ErrInvalidMessage
+	}
+	if (m.Type == MessageText && !utf8.Valid(m.Data)) || (m.Type == MessageJSON && !json.Valid(m.Data)) {
+		return nil, ErrInvalidMessage
+	}
+	return m, nil
+}
+
+// bytesToTrytes encodes every byte of b in 2 trytes like AsciiToTrytes.
+func bytesToTrytes(b []byte) Trytes {
+	t := make([]byte, 2*len(b))
+	for i, c := range b {
+		t[2*i], t[2*i+1] = TryteAlphabet[c%27], TryteAlphabet[c/27]
+	}
+	return Trytes(t)
+}
+
+// trytesToBytes returns the bytes encoded by bytesToTrytes in t, and false
+// if t doesn't encode bytes.
+func trytesToBytes(t Trytes) ([]byte, bool) {
+	if len(t)%2 != 0 {
+		return nil, false
+	}
+
+	b := make([]byte, len(t)/2)
+	for i := range b {
+		lo, hi := strings.IndexByte(TryteAlphabet, t[2*i]), strings.IndexByte(TryteAlphabet, t[2*i+1])
+		v := lo + hi*27
+		if lo < 0 || hi < 0 || v > 255 {
+			return nil, false
+		}
+		b[i] = byte(v)
+	}
+	return b, true
+}
+
+// splitFragments splits msg in fragments of a signature message fragment,
+// at least one.
+func splitFragments(msg Trytes) []Trytes {
+	frags := make([]Trytes, 0, len(msg)/sigSize+1)
+	for len(msg) > sigSize {
+		frags = append(frags, msg[:sigSize])
+		msg = msg[sigSize:]
+	}
+	return append(frags, msg)
+}
+
+// BundleMessage is the message sent to an output of a bundle.
+type BundleMessage struct {
+	// Index is the index of the first transaction of the output.
+	Index   int
+	Address Address
+	Message *Message
+}
+
+// Messages reassembles the messages of the outputs of bs, an output being the
+// consecutive transactions to the same address which are not an input.
+// Messages without a header are returned as MessageTrytes and outputs
+// without a message are skipped.
+func (bs Bundle) Messages() []BundleMessage {
+	var msgs []BundleMessage
+	for i := 0; i < len(bs); {
+		j := i + 1
+		for j < len(bs) && bs[j].Address == bs[i].Address {
+			j++
+		}
+
+		if !bs[i].isInput() {
+			var b strings.Builder
+			for k := i; k < j; k++ {
+				b.WriteString(string(bs[k].SignatureMessageFragment))
+			}
+
+			m := decodeFragments(Trytes(b.String()))
+			if len(m.Data) > 0 || m.Type != MessageTrytes {
+				msgs = append(msgs, BundleMessage{Index: i, Address: bs[i].Address, Message: m})
+			}
+		}
+		i = j
+	}
+	return msgs
+}
+
+// decodeFragments returns the message in the fragments t, as MessageTrytes
+// if it has no header.
+func decodeFragments(t Trytes) *Message {
+	m, err := DecodeMessage(t)
+	if err != nil {
+		m = &Message{Type: MessageTrytes, Data: []byte(strings.TrimRight(string(t), "9"))}
+	}
+	return m
+}
+
+// isInput returns true if t spends from its address: it has a negative value
+// in the IOTA layout and no range proof in the others.
+func (t *Transaction) isInput() bool {
+	if t.Version == LayoutVersionIOTA {
+		return t.Value.Trits().Int() < 0
+	}
+	return isEmptyTrytes(t.RangeProof)
+}
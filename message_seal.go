@@ -0,0 +1,186 @@
+package giota
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1"
+)
+
+// Messages are sealed with AES-256-GCM. The key is derived from the ECDH
+// secret of the sender and the receiver of an output, and the nonce from the
+// bundle hash and the index of the first transaction of the output, so that
+// it is never reused with the same key. The address of the output is
+// authenticated with the message.
+//
+// The messages of a bundle are sealed with the secret of the key of the
+// sender and the public key of the address of the output, and start with the
+// compressed public key of the sender, so that the receiver can open them
+// whether the bundle has an input or not.
+const (
+	messageKeyLabel = "giota message key"
+	messageTagSize  = 16
+	messageKeySize  = 33
+)
+
+// errors of sealed messages
+var (
+	ErrMessageNotSealed = errors.New("message is not sealed")
+	ErrMessageOpen      = errors.New("message can't be opened with the key")
+)
+
+// MessageSecret returns the ECDH secret of a private key and the public key
+// of the other party.
+func MessageSecret(key *secp256k1.PrivateKey, pub *secp256k1.PublicKey) []byte {
+	return secp256k1.GenerateSharedSecret(key, pub)
+}
+
+// SealMessage encrypts msg, the message of the output to adr starting at
+// index in the bundle whose hash is bundle, with secret.
+func SealMessage(secret []byte, bundle Trytes, index int, adr Address, msg Trytes) (*Message, error) {
+	aead, nonce, err := messageAEAD(secret, bundle, index)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: MessageEncrypted, Data: aead.Seal(nil, nonce, []byte(msg), []byte(adr))}, nil
+}
+
+// OpenMessage decrypts the message sealed by SealMessage with the same
+// arguments.
+func OpenMessage(secret []byte, bundle Trytes, index int, adr Address, m *Message) (Trytes, error) {
+	if m.Type != MessageEncrypted {
+		return "", ErrMessageNotSealed
+	}
+
+	aead, nonce, err := messageAEAD(secret, bundle, index)
+	if err != nil {
+		return "", err
+	}
+	b, err := aead.Open(nil, nonce, m.Data, []byte(adr))
+	if err != nil {
+		return "", ErrMessageOpen
+	}
+
+	msg := Trytes(b)
+	if err := msg.IsValid(); err != nil {
+		return "", err
+	}
+	return msg, nil
+}
+
+func messageAEAD(secret []byte, bundle Trytes, index int) (cipher.AEAD, []byte, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(messageKeyLabel))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var i [8]byte
+	binary.BigEndian.PutUint64(i[:], uint64(index))
+	h := sha256.New()
+	h.Write([]byte(bundle))
+	h.Write(i[:])
+	return aead, h.Sum(nil)[:aead.NonceSize()], nil
+}
+
+// sealedMessageSize returns the number of trytes of the sealed message of n
+// trytes.
+func sealedMessageSize(n int) int {
+	return messageHeaderSize + 2*(n+messageTagSize)
+}
+
+// outputMessage returns the message of tr to put in the bundle, which is a
+// placeholder of the size of the sealed message if it is encrypted.
+func outputMessage(tr *Transfer) Trytes {
+	if tr.EncryptMessage {
+		return pad("", sealedMessageSize(messageKeySize+len(tr.Message)))
+	}
+	return tr.Message
+}
+
+// sealMessages replaces the placeholders of the encrypted messages of the
+// outputs of trs in the finalized bundle bs with the messages sealed by key.
+func sealMessages(bs Bundle, trs []Transfer, key *secp256k1.PrivateKey) error {
+	i := 0
+	for k := range trs {
+		tr := &trs[k]
+		n := len(splitFragments(outputMessage(tr)))
+		if tr.EncryptMessage {
+			pub, err := tr.Address.DecodePubKey()
+			if err != nil {
+				return err
+			}
+
+			m, err := sealBundleMessage(key, secp256k1.NewPublicKey(pub.Coords()), bs[i].Bundle, i, bs[i].Address, tr.Message)
+			if err != nil {
+				return err
+			}
+			t, err := m.Encode()
+			if err != nil {
+				return err
+			}
+			for j, f := range splitFragments(t) {
+				bs[i+j].SignatureMessageFragment = pad(f, sigSize)
+			}
+		}
+		i += n
+	}
+	return nil
+}
+
+// sealBundleMessage seals msg to pub like SealMessage with the secret of key
+// and pub, and puts the public key of key before the sealed data.
+func sealBundleMessage(key *secp256k1.PrivateKey, pub *secp256k1.PublicKey, bundle Trytes, index int, adr Address, msg Trytes) (*Message, error) {
+	m, err := SealMessage(MessageSecret(key, pub), bundle, index, adr, msg)
+	if err != nil {
+		return nil, err
+	}
+	m.Data = append(key.PubKey().SerializeCompressed(), m.Data...)
+	return m, nil
+}
+
+// openBundleMessage opens the message sealed by sealBundleMessage with the
+// key of its receiver.
+func openBundleMessage(key *secp256k1.PrivateKey, bundle Trytes, index int, adr Address, m *Message) (Trytes, error) {
+	if m.Type != MessageEncrypted {
+		return "", ErrMessageNotSealed
+	}
+	if len(m.Data) < messageKeySize {
+		return "", ErrMessageOpen
+	}
+
+	pub, err := secp256k1.ParsePubKey(m.Data[:messageKeySize])
+	if err != nil {
+		return "", ErrMessageOpen
+	}
+	sealed := &Message{Type: MessageEncrypted, Data: m.Data[messageKeySize:]}
+	return OpenMessage(MessageSecret(key, pub), bundle, index, adr, sealed)
+}
+
+// DecryptMessages returns the messages of bs like Messages, with the messages
+// sealed to key opened and decoded. Messages sealed to another key are left
+// sealed.
+func (bs Bundle) DecryptMessages(key *secp256k1.PrivateKey) []BundleMessage {
+	msgs := bs.Messages()
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Message.Type != MessageEncrypted {
+			continue
+		}
+		t, err := openBundleMessage(key, bs[m.Index].Bundle, m.Index, m.Address, m.Message)
+		if err != nil {
+			continue
+		}
+		m.Message = decodeFragments(t)
+	}
+	return msgs
+}
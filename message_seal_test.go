@@ -0,0 +1,157 @@
+package giota
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1"
+)
+
+const testMessageSeed = Trytes("CLBHL9DOQXUHBWORNBHNPUB9JQUHYLLXXCJQRJVRJXYHAAISJPTDA9ZFVLPPNAHLDNMDDMGYXEDVROMQV")
+
+// testMessageKey returns the key at index and the address of its public key.
+func testMessageKey(t *testing.T, index int) (*secp256k1.PrivateKey, Address) {
+	ai := &AddressInfo{Seed: testMessageSeed, Index: index}
+	if err := ai.Secret(); err != nil {
+		t.Fatal(err)
+	}
+	sk, err := ai.Sk.SecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := secp256k1.PrivKeyFromBytes(sk[:])
+
+	adr, err := ai.Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, adr
+}
+
+func TestSealMessage(t *testing.T) {
+	secret := []byte("a shared secret of 32 bytes.....")
+	adr := Address(strings.Repeat("A", 81))
+	msg := Trytes("INVOICE9NUMBER9ONE")
+
+	m, err := SealMessage(secret, EmptyHash, 3, adr, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr) != sealedMessageSize(len(msg)) {
+		t.Errorf("sealed message has %d trytes, want %d", len(tr), sealedMessageSize(len(msg)))
+	}
+
+	opened, err := OpenMessage(secret, EmptyHash, 3, adr, m)
+	if err != nil || opened != msg {
+		t.Fatalf("opened %s, %v", opened, err)
+	}
+
+	for _, c := range []struct {
+		secret []byte
+		bundle Trytes
+		index  int
+		adr    Address
+	}{
+		{[]byte("another secret"), EmptyHash, 3, adr},
+		{secret, pad("A", 81), 3, adr},
+		{secret, EmptyHash, 4, adr},
+		{secret, EmptyHash, 3, Address(strings.Repeat("B", 81))},
+	} {
+		if _, err := OpenMessage(c.secret, c.bundle, c.index, c.adr, m); err != ErrMessageOpen {
+			t.Errorf("opening with %+v returned %v", c, err)
+		}
+	}
+
+	if _, err := OpenMessage(secret, EmptyHash, 3, adr, NewTextMessage("clear")); err != ErrMessageNotSealed {
+		t.Errorf("opening a clear message returned %v", err)
+	}
+}
+
+func TestDecryptMessages(t *testing.T) {
+	senderKey, senderAdr := testMessageKey(t, 0)
+	receiverKey, receiverAdr := testMessageKey(t, 1)
+	_, otherAdr := testMessageKey(t, 2)
+
+	text, err := NewTextMessage("invoice 42").Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trs := []Transfer{
+		{Address: receiverAdr, Message: text, EncryptMessage: true},
+		{Address: otherAdr, Message: "CLEAR"},
+	}
+
+	proof := pad("ABC", RangeProofTrinarySize/3)
+	var bs Bundle
+	for k := range trs {
+		for _, f := range splitFragments(outputMessage(&trs[k])) {
+			bs = append(bs, Transaction{
+				Address:                  trs[k].Address,
+				RangeProof:               proof,
+				SignatureMessageFragment: pad(f, sigSize),
+				Bundle:                   EmptyHash,
+			})
+		}
+	}
+	bs = append(bs, Transaction{
+		Address:                  senderAdr,
+		RangeProof:               pad("", RangeProofTrinarySize/3),
+		SignatureMessageFragment: pad("", sigSize),
+		Bundle:                   EmptyHash,
+	})
+
+	if err := sealMessages(bs, trs, senderKey); err != nil {
+		t.Fatal(err)
+	}
+	if m := bs.Messages(); len(m) != 2 || m[0].Message.Type != MessageEncrypted {
+		t.Fatalf("messages before decryption are %+v", m)
+	}
+
+	msgs := bs.DecryptMessages(receiverKey)
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages, want 2", len(msgs))
+	}
+	if s, err := msgs[0].Message.Text(); s != "invoice 42" || err != nil {
+		t.Errorf("decrypted message is %q, %v", s, err)
+	}
+	if m := msgs[1].Message; m.Type != MessageTrytes || string(m.Data) != "CLEAR" {
+		t.Errorf("clear message is %d %q", m.Type, m.Data)
+	}
+
+	if m := bs[:1].DecryptMessages(receiverKey); len(m) != 1 || m[0].Message.Type != MessageText {
+		t.Errorf("messages of the bundle without input are %+v", m)
+	}
+}
+
+func TestPrepareTransfersEncryptedMessage(t *testing.T) {
+	receiverKey, adr := testMessageKey(t, 1)
+	msg, err := NewTextMessage("invoice 43").Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trs := []Transfer{{Address: adr, Message: msg, EncryptMessage: true}}
+	inputs := []AddressInfo{{Seed: testMessageSeed, Index: 0}}
+	if err := inputs[0].Secret(); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := PrepareTransfers(&scanMockAPI{}, testMessageSeed, trs, inputs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := bs.Messages(); len(m) != 1 || m[0].Message.Type != MessageEncrypted {
+		t.Fatalf("messages of the bundle are %+v", m)
+	}
+
+	msgs := bs.DecryptMessages(receiverKey)
+	if len(msgs) != 1 {
+		t.Fatalf("%d messages, want 1", len(msgs))
+	}
+	if s, err := msgs[0].Message.Text(); s != "invoice 43" || err != nil {
+		t.Errorf("decrypted message is %q, %v", s, err)
+	}
+}
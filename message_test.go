@@ -0,0 +1,174 @@
+package giota
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMessageEncode(t *testing.T) {
+	j, err := NewJSONMessage(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*Message{
+		NewTextMessage("héllo tangle ✓"),
+		NewTextMessage(""),
+		NewBytesMessage([]byte{0, 1, 127, 128, 255}),
+		j,
+	} {
+		tr, err := m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.IsValid(); err != nil {
+			t.Fatalf("invalid trytes %s", tr)
+		}
+
+		d, err := DecodeMessage(pad(tr, len(tr)+100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Type != m.Type || !bytes.Equal(d.Data, m.Data) {
+			t.Errorf("decoded %+v, want %+v", d, m)
+		}
+	}
+
+	var v map[string]int
+	if err := j.JSON(&v); err != nil || v["a"] != 1 {
+		t.Errorf("JSON returned %v, %v", v, err)
+	}
+	if _, err := j.Text(); err != ErrInvalidMessageType {
+		t.Errorf("Text of a JSON message returned %v", err)
+	}
+}
+
+func TestMessageInvalid(t *testing.T) {
+	if _, err := NewTextMessage("\xff").Encode(); err != ErrInvalidMessage {
+		t.Errorf("invalid UTF-8 text returned %v", err)
+	}
+	if _, err := (&Message{Type: MessageJSON, Data: []byte("{")}).Encode(); err != ErrInvalidMessage {
+		t.Errorf("invalid JSON returned %v", err)
+	}
+	if _, err := (&Message{Type: MessageTrytes}).Encode(); err != ErrInvalidMessageType {
+		t.Errorf("trytes message returned %v", err)
+	}
+
+	tr, err := NewTextMessage("hello").Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Trytes{"", "HELLO", tr[:len(tr)-1], "MSG" + tr[3:messageHeaderSize]} {
+		if _, err := DecodeMessage(s); err != ErrInvalidMessage {
+			t.Errorf("%s returned %v", s, err)
+		}
+	}
+}
+
+func TestSplitFragments(t *testing.T) {
+	for _, c := range []struct {
+		length, frags int
+	}{
+		{0, 1},
+		{1, 1},
+		{sigSize, 1},
+		{sigSize + 1, 2},
+		{3000, 2},
+		{2 * sigSize, 2},
+		{2*sigSize + 1, 3},
+	} {
+		msg := Trytes(strings.Repeat("A", c.length))
+		frags := splitFragments(msg)
+		if len(frags) != c.frags {
+			t.Errorf("%d trytes are split in %d fragments, want %d", c.length, len(frags), c.frags)
+			continue
+		}
+
+		var joined Trytes
+		for _, f := range frags {
+			if len(f) > sigSize {
+				t.Errorf("fragment of %d trytes", len(f))
+			}
+			joined += f
+		}
+		if joined != msg {
+			t.Errorf("fragments of %d trytes don't join to the message", c.length)
+		}
+	}
+}
+
+func TestBundleMessages(t *testing.T) {
+	long, err := NewBytesMessage(bytes.Repeat([]byte{200}, sigSize+10)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := NewTextMessage("hi").Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof := pad("ABC", RangeProofTrinarySize/3)
+	empty := pad("", RangeProofTrinarySize/3)
+	adr := func(c string) Address {
+		return Address(strings.Repeat(c, 81))
+	}
+
+	var bs Bundle
+	add := func(a Address, rp, msg Trytes) {
+		for _, f := range splitFragments(msg) {
+			bs = append(bs, Transaction{
+				Address:                  a,
+				RangeProof:               rp,
+				SignatureMessageFragment: pad(f, sigSize),
+			})
+		}
+	}
+	add(adr("A"), proof, long)
+	add(adr("B"), proof, text)
+	add(adr("C"), proof, "")
+	add(adr("D"), proof, "RAW")
+	add(adr("E"), empty, text)
+
+	msgs := bs.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("%d messages, want 3", len(msgs))
+	}
+
+	if m := msgs[0]; m.Index != 0 || m.Address != adr("A") || m.Message.Type != MessageBytes || len(m.Message.Data) != sigSize+10 {
+		t.Errorf("first message is %d %s %d %d bytes", m.Index, m.Address, m.Message.Type, len(m.Message.Data))
+	}
+	if s, err := msgs[1].Message.Text(); msgs[1].Index != len(splitFragments(long)) || s != "hi" || err != nil {
+		t.Errorf("second message is %d %q %v", msgs[1].Index, s, err)
+	}
+	if m := msgs[2]; m.Address != adr("D") || m.Message.Type != MessageTrytes || string(m.Message.Data) != "RAW" {
+		t.Errorf("third message is %s %d %q", m.Address, m.Message.Type, m.Message.Data)
+	}
+}
+
+func TestPrepareTransfersLongMessage(t *testing.T) {
+	_, adr := testMessageKey(t, 1)
+	msg, err := NewBytesMessage(bytes.Repeat([]byte{200}, sigSize+10)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trs := []Transfer{{Address: adr, Message: msg}}
+	inputs := []AddressInfo{{Seed: testMessageSeed, Index: 0}}
+	if err := inputs[0].Secret(); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := PrepareTransfers(&scanMockAPI{}, testMessageSeed, trs, inputs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bs) != len(splitFragments(msg)) {
+		t.Fatalf("bundle has %d transactions, want %d", len(bs), len(splitFragments(msg)))
+	}
+	if err := bs.IsValid(); err != nil {
+		t.Errorf("bundle is invalid: %v", err)
+	}
+	if m := bs.Messages(); len(m) != 1 || len(m[0].Message.Data) != sigSize+10 {
+		t.Errorf("messages of the bundle are %+v", m)
+	}
+}
@@ -0,0 +1,521 @@
+package giota
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errors used by NodePool
+var (
+	ErrNoHealthyNodes     = errors.New("no healthy node in pool")
+	ErrNotEnoughNodes     = errors.New("not enough healthy nodes for a quorum read")
+	ErrQuorumDisagreement = errors.New("nodes disagree on the result of a quorum read")
+)
+
+// DefaultMaxMilestoneLag is the number of milestones a node may fall behind the
+// most recent milestone known to the pool before it is considered unhealthy.
+const DefaultMaxMilestoneLag = 2
+
+// NodeStatus is the health of a single node in a NodePool.
+type NodeStatus struct {
+	Endpoint       string
+	Healthy        bool
+	MilestoneIndex int64
+	Lag            int64
+	LastCheck      time.Time
+	Err            error
+}
+
+var _ NodeAPI = (*NodePool)(nil)
+
+type poolNode struct {
+	api    *API
+	status NodeStatus
+}
+
+// NodePool spreads API calls over several nodes. It implements NodeAPI, so it
+// can be used wherever a single node is used.
+// Reads are retried on the next healthy node when a node fails, writes are sent
+// to the healthiest node only, and GetBalances and GetInclusionStates can be
+// checked against a quorum of nodes.
+type NodePool struct {
+	// MaxMilestoneLag is the number of milestones a node may lag behind.
+	MaxMilestoneLag int64
+
+	// Quorum is the number of nodes that must agree on GetBalances and
+	// GetInclusionStates. Quorum reads are disabled if Quorum <= 1.
+	Quorum int
+
+	mu    sync.RWMutex
+	nodes []*poolNode
+	stop  chan struct{}
+}
+
+// NewNodePool returns a NodePool for endpoints using the (optional) http.Client c.
+// All nodes are considered healthy until the first health check. Commands are
+// not retried on the same node since the pool fails over to the next one.
+func NewNodePool(endpoints []string, c *http.Client) *NodePool {
+	p := &NodePool{
+		MaxMilestoneLag: DefaultMaxMilestoneLag,
+	}
+
+	for _, e := range endpoints {
+		api := NewAPI(e, c)
+		api.SetRetryPolicy(NoRetry)
+		p.nodes = append(p.nodes, &poolNode{
+			api: api,
+			status: NodeStatus{
+				Endpoint: api.endpoint,
+				Healthy:  true,
+			},
+		})
+	}
+	return p
+}
+
+// CheckHealth calls GetNodeInfo on all nodes and marks the nodes that do not
+// respond or lag behind the latest milestone as unhealthy.
+func (p *NodePool) CheckHealth() error {
+	infos := make([]*GetNodeInfoResponse, len(p.nodes))
+	errs := make([]error, len(p.nodes))
+
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *poolNode) {
+			infos[i], errs[i] = n.api.GetNodeInfo()
+			wg.Done()
+		}(i, n)
+	}
+	wg.Wait()
+
+	var latest int64
+	for i, info := range infos {
+		if errs[i] == nil && info.LatestMilestoneIndex > latest {
+			latest = info.LatestMilestoneIndex
+		}
+	}
+
+	now := time.Now()
+	healthy := 0
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, n := range p.nodes {
+		st := &n.status
+		st.LastCheck = now
+		st.Err = errs[i]
+
+		if errs[i] != nil {
+			st.Healthy = false
+			continue
+		}
+
+		st.MilestoneIndex = infos[i].LatestSolidSubtangleMilestoneIndex
+		st.Lag = latest - st.MilestoneIndex
+		st.Healthy = st.Lag <= p.MaxMilestoneLag
+		if st.Healthy {
+			healthy++
+		}
+	}
+
+	if healthy == 0 {
+		return ErrNoHealthyNodes
+	}
+	return nil
+}
+
+// StartHealthChecks runs CheckHealth now and then every interval until Stop is called.
+func (p *NodePool) StartHealthChecks(interval time.Duration) {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stop = stop
+	p.mu.Unlock()
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			p.CheckHealth()
+
+			select {
+			case <-t.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the health checks started by StartHealthChecks.
+func (p *NodePool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+// Status returns the health of all nodes in the pool.
+func (p *NodePool) Status() []NodeStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	st := make([]NodeStatus, len(p.nodes))
+	for i, n := range p.nodes {
+		st[i] = n.status
+	}
+	return st
+}
+
+// healthy returns the healthy nodes, the ones with the smallest lag first.
+func (p *NodePool) healthy() []*poolNode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var nodes []*poolNode
+	for _, n := range p.nodes {
+		if n.status.Healthy {
+			nodes = append(nodes, n)
+		}
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].status.Lag < nodes[j].status.Lag
+	})
+	return nodes
+}
+
+// fail marks n as unhealthy if err shows that the node could not be reached.
+func (p *NodePool) fail(n *poolNode, err error) {
+	if _, ok := err.(*NetworkError); !ok {
+		return
+	}
+
+	p.mu.Lock()
+	n.status.Healthy = false
+	n.status.Err = err
+	p.mu.Unlock()
+}
+
+// read calls fn with the healthy nodes in turn until one succeeds.
+func (p *NodePool) read(fn func(api *API) error) error {
+	nodes := p.healthy()
+	if len(nodes) == 0 {
+		return ErrNoHealthyNodes
+	}
+
+	var err error
+	for _, n := range nodes {
+		if err = fn(n.api); err == nil {
+			return nil
+		}
+		p.fail(n, err)
+	}
+	return err
+}
+
+// write calls fn with the healthiest node.
+func (p *NodePool) write(fn func(api *API) error) error {
+	nodes := p.healthy()
+	if len(nodes) == 0 {
+		return ErrNoHealthyNodes
+	}
+
+	err := fn(nodes[0].api)
+	if err != nil {
+		p.fail(nodes[0], err)
+	}
+	return err
+}
+
+// writeAll calls fn with all healthy nodes and succeeds if one of them succeeds.
+func (p *NodePool) writeAll(fn func(api *API) error) error {
+	nodes := p.healthy()
+	if len(nodes) == 0 {
+		return ErrNoHealthyNodes
+	}
+
+	errs := make([]error, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *poolNode) {
+			errs[i] = fn(n.api)
+			wg.Done()
+		}(i, n)
+	}
+	wg.Wait()
+
+	var err error
+	for i, n := range nodes {
+		if errs[i] == nil {
+			return nil
+		}
+		p.fail(n, errs[i])
+		err = errs[i]
+	}
+	return err
+}
+
+// quorum calls fn with Quorum healthy nodes in parallel and returns their results.
+// Nodes which fail are replaced by the next healthy node.
+func (p *NodePool) quorum(fn func(api *API) (interface{}, error)) ([]interface{}, error) {
+	nodes := p.healthy()
+
+	var (
+		results []interface{}
+		lastErr error
+	)
+
+	for next := 0; len(results) < p.Quorum; {
+		need := p.Quorum - len(results)
+		if next+need > len(nodes) {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, ErrNotEnoughNodes
+		}
+
+		batch := nodes[next : next+need]
+		next += need
+
+		rs := make([]interface{}, len(batch))
+		errs := make([]error, len(batch))
+
+		var wg sync.WaitGroup
+		for i, n := range batch {
+			wg.Add(1)
+			go func(i int, n *poolNode) {
+				rs[i], errs[i] = fn(n.api)
+				wg.Done()
+			}(i, n)
+		}
+		wg.Wait()
+
+		for i, n := range batch {
+			if errs[i] != nil {
+				p.fail(n, errs[i])
+				lastErr = errs[i]
+				continue
+			}
+			results = append(results, rs[i])
+		}
+	}
+	return results, nil
+}
+
+// GetNodeInfo calls GetNodeInfo API.
+func (p *NodePool) GetNodeInfo() (*GetNodeInfoResponse, error) {
+	var resp *GetNodeInfoResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.GetNodeInfo()
+		return
+	})
+	return resp, err
+}
+
+// CheckConsistency calls CheckConsistency API.
+func (p *NodePool) CheckConsistency(tails []Trytes) (*CheckConsistencyResponse, error) {
+	var resp *CheckConsistencyResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.CheckConsistency(tails)
+		return
+	})
+	return resp, err
+}
+
+// GetNeighbors calls GetNeighbors API of the healthiest node.
+func (p *NodePool) GetNeighbors() (*GetNeighborsResponse, error) {
+	var resp *GetNeighborsResponse
+	err := p.write(func(api *API) (err error) {
+		resp, err = api.GetNeighbors()
+		return
+	})
+	return resp, err
+}
+
+// AddNeighbors calls AddNeighbors API of the healthiest node.
+func (p *NodePool) AddNeighbors(uris []string) (*AddNeighborsResponse, error) {
+	var resp *AddNeighborsResponse
+	err := p.write(func(api *API) (err error) {
+		resp, err = api.AddNeighbors(uris)
+		return
+	})
+	return resp, err
+}
+
+// RemoveNeighbors calls RemoveNeighbors API of the healthiest node.
+func (p *NodePool) RemoveNeighbors(uris []string) (*RemoveNeighborsResponse, error) {
+	var resp *RemoveNeighborsResponse
+	err := p.write(func(api *API) (err error) {
+		resp, err = api.RemoveNeighbors(uris)
+		return
+	})
+	return resp, err
+}
+
+// GetTips calls GetTips API.
+func (p *NodePool) GetTips() (*GetTipsResponse, error) {
+	var resp *GetTipsResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.GetTips()
+		return
+	})
+	return resp, err
+}
+
+// FindTransactions calls FindTransactions API.
+func (p *NodePool) FindTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error) {
+	var resp *FindTransactionsResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.FindTransactions(ft)
+		return
+	})
+	return resp, err
+}
+
+// GetTrytes calls GetTrytes API.
+func (p *NodePool) GetTrytes(hashes []Trytes) (*GetTrytesResponse, error) {
+	var resp *GetTrytesResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.GetTrytes(hashes)
+		return
+	})
+	return resp, err
+}
+
+// GetInclusionStates calls GetInclusionStates API. If Quorum is set, the states
+// returned by Quorum nodes must be equal.
+func (p *NodePool) GetInclusionStates(tx []Trytes, tips []Trytes) (*GetInclusionStatesResponse, error) {
+	if p.Quorum <= 1 {
+		var resp *GetInclusionStatesResponse
+		err := p.read(func(api *API) (err error) {
+			resp, err = api.GetInclusionStates(tx, tips)
+			return
+		})
+		return resp, err
+	}
+
+	rs, err := p.quorum(func(api *API) (interface{}, error) {
+		return api.GetInclusionStates(tx, tips)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	first := rs[0].(*GetInclusionStatesResponse)
+	for _, r := range rs[1:] {
+		if !reflect.DeepEqual(first.States, r.(*GetInclusionStatesResponse).States) {
+			return nil, ErrQuorumDisagreement
+		}
+	}
+	return first, nil
+}
+
+// Balances calls GetBalances API and returns address-balance pair struct.
+func (p *NodePool) Balances(adr []Address, seed Trytes) (Balances, error) {
+	r, err := p.GetBalances(adr, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBalances(r, seed)
+}
+
+// GetBalances calls GetBalances API. If Quorum is set, the balances returned
+// by Quorum nodes must be equal.
+func (p *NodePool) GetBalances(adr []Address, threshold int64) (*GetBalancesResponse, error) {
+	if p.Quorum <= 1 {
+		var resp *GetBalancesResponse
+		err := p.read(func(api *API) (err error) {
+			resp, err = api.GetBalances(adr, threshold)
+			return
+		})
+		return resp, err
+	}
+
+	rs, err := p.quorum(func(api *API) (interface{}, error) {
+		return api.GetBalances(adr, threshold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	first := rs[0].(*GetBalancesResponse)
+	for _, r := range rs[1:] {
+		if !reflect.DeepEqual(first.Balances, r.(*GetBalancesResponse).Balances) {
+			return nil, ErrQuorumDisagreement
+		}
+	}
+	return first, nil
+}
+
+// GetTransactionsToApprove calls GetTransactionsToApprove API.
+func (p *NodePool) GetTransactionsToApprove(depth, numWalks int64, reference Trytes) (*GetTransactionsToApproveResponse, error) {
+	var resp *GetTransactionsToApproveResponse
+	err := p.read(func(api *API) (err error) {
+		resp, err = api.GetTransactionsToApprove(depth, numWalks, reference)
+		return
+	})
+	return resp, err
+}
+
+// AttachToTangle calls AttachToTangle API of the healthiest node.
+func (p *NodePool) AttachToTangle(att *AttachToTangleRequest) (*AttachToTangleResponse, error) {
+	var resp *AttachToTangleResponse
+	err := p.write(func(api *API) (err error) {
+		resp, err = api.AttachToTangle(att)
+		return
+	})
+	return resp, err
+}
+
+// InterruptAttachingToTangle calls InterruptAttachingToTangle API of all nodes.
+func (p *NodePool) InterruptAttachingToTangle() error {
+	var err error
+	for _, n := range p.nodes {
+		if e := n.api.InterruptAttachingToTangle(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// BroadcastTransactions calls BroadcastTransactions API of all healthy nodes.
+func (p *NodePool) BroadcastTransactions(trytes []Transaction) error {
+	return p.writeAll(func(api *API) error {
+		return api.BroadcastTransactions(trytes)
+	})
+}
+
+// StoreTransactions calls StoreTransactions API of all healthy nodes.
+func (p *NodePool) StoreTransactions(trytes []Transaction) error {
+	return p.writeAll(func(api *API) error {
+		return api.StoreTransactions(trytes)
+	})
+}
+
+// GetLatestInclusion takes the most recent solid milestone as returned by getNodeInfo
+// and uses it to get the inclusion states of a list of transaction hashes
+func (p *NodePool) GetLatestInclusion(hash []Trytes) ([]bool, error) {
+	var states []bool
+	err := p.read(func(api *API) (err error) {
+		states, err = api.GetLatestInclusion(hash)
+		return
+	})
+	return states, err
+}
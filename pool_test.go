@@ -0,0 +1,131 @@
+package giota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type poolTestNode struct {
+	milestone int64
+	balance   string
+	calls     int32 // accessed atomically
+}
+
+func (n *poolTestNode) start() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cmd struct {
+			Command string `json:"command"`
+		}
+		json.NewDecoder(r.Body).Decode(&cmd)
+		atomic.AddInt32(&n.calls, 1)
+
+		switch cmd.Command {
+		case "getNodeInfo":
+			json.NewEncoder(w).Encode(&GetNodeInfoResponse{
+				AppName:                            "test",
+				LatestMilestoneIndex:               n.milestone,
+				LatestSolidSubtangleMilestoneIndex: n.milestone,
+			})
+		case "getBalances":
+			json.NewEncoder(w).Encode(&GetBalancesResponse{
+				Balances:       []string{n.balance},
+				MilestoneIndex: n.milestone,
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&ErrorResponse{Error: "unknown command"})
+		}
+	}))
+}
+
+func TestNodePoolHealth(t *testing.T) {
+	synced := &poolTestNode{milestone: 100}
+	lagging := &poolTestNode{milestone: 90}
+
+	s1 := synced.start()
+	defer s1.Close()
+	s2 := lagging.start()
+	defer s2.Close()
+	s3 := (&poolTestNode{}).start()
+	s3.Close()
+
+	p := NewNodePool([]string{s3.URL, s2.URL, s1.URL}, nil)
+	if err := p.CheckHealth(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := p.Status()
+	switch {
+	case st[0].Healthy:
+		t.Error("CheckHealth() marked an unreachable node as healthy")
+	case st[1].Healthy || st[1].Lag != 10:
+		t.Errorf("CheckHealth() did not detect the lagging node: %#v", st[1])
+	case !st[2].Healthy:
+		t.Errorf("CheckHealth() marked a synced node as unhealthy: %#v", st[2])
+	}
+
+	atomic.StoreInt32(&synced.calls, 0)
+	if _, err := p.GetNodeInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&synced.calls) != 1 {
+		t.Error("GetNodeInfo() was not routed to the healthy node")
+	}
+}
+
+func TestNodePoolFailover(t *testing.T) {
+	dead := (&poolTestNode{}).start()
+	dead.Close()
+
+	alive := &poolTestNode{milestone: 1}
+	s := alive.start()
+	defer s.Close()
+
+	p := NewNodePool([]string{dead.URL, s.URL}, nil)
+
+	resp, err := p.GetNodeInfo()
+	if err != nil {
+		t.Fatalf("GetNodeInfo() expected err to be nil but got %v", err)
+	}
+	if resp.AppName != "test" {
+		t.Errorf("GetNodeInfo() returned invalid response: %#v", resp)
+	}
+	if p.Status()[0].Healthy {
+		t.Error("failed node was not marked as unhealthy")
+	}
+}
+
+func TestNodePoolQuorum(t *testing.T) {
+	a := &poolTestNode{milestone: 1, balance: "ABC"}
+	b := &poolTestNode{milestone: 1, balance: "ABC"}
+	c := &poolTestNode{milestone: 1, balance: "XYZ"}
+
+	sa, sb, sc := a.start(), b.start(), c.start()
+	defer sa.Close()
+	defer sb.Close()
+	defer sc.Close()
+
+	p := NewNodePool([]string{sa.URL, sb.URL}, nil)
+	p.Quorum = 2
+	resp, err := p.GetBalances([]Address{EmptyAddress}, 100)
+	if err != nil {
+		t.Fatalf("GetBalances() expected err to be nil but got %v", err)
+	}
+	if resp.Balances[0] != "ABC" {
+		t.Errorf("GetBalances() returned invalid balances: %v", resp.Balances)
+	}
+
+	p = NewNodePool([]string{sa.URL, sc.URL}, nil)
+	p.Quorum = 2
+	if _, err := p.GetBalances([]Address{EmptyAddress}, 100); err != ErrQuorumDisagreement {
+		t.Errorf("GetBalances() expected ErrQuorumDisagreement but got %v", err)
+	}
+
+	p.Quorum = 3
+	if _, err := p.GetBalances([]Address{EmptyAddress}, 100); err != ErrNotEnoughNodes {
+		t.Errorf("GetBalances() expected ErrNotEnoughNodes but got %v", err)
+	}
+}
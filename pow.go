@@ -0,0 +1,384 @@
+package giota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PowFunc is the func type for PoW
+type PowFunc func(Trytes, int) (Trytes, error)
+
+// errors of PoW searches
+var (
+	ErrUnknownPow    = errors.New("unknown PoW implementation")
+	ErrInvalidMWM    = errors.New("invalid minimum weight magnitude")
+	ErrNonceNotFound = errors.New("no nonce was found")
+	ErrPowRounds     = errors.New("PoW implementation only supports Curl-P-81")
+)
+
+// PowProcs is number of concurrent processes (default is NumCPU()-1)
+var PowProcs int
+
+// powImpl is a PoW implementation of the package.
+type powImpl struct {
+	search powSearch
+
+	// procs is the number of searches run for a job, PowProcs if zero.
+	procs int
+
+	// anyRounds is true if search hashes with the rounds of the job,
+	// otherwise it only searches nonces of Curl-P-81.
+	anyRounds bool
+}
+
+// powSearch searches the nonces of the nth share of j until one is found or
+// j is stopped. It returns the trits j.mid[j.start:j.end] of the nonce found.
+type powSearch func(j *powJob, n int) Trits
+
+var powImpls = make(map[string]powImpl)
+
+// powLanes64 and powLanes128 are the trits of the nonce giving a different
+// value to every lane of 64 and 128 bits searches, powShareTrits the trits
+// after them which split the nonces between the searches of a job.
+const (
+	powLanes64    = 4
+	powLanes128   = 5
+	powShareTrits = 27
+
+	// powMinNonceTrits leaves room to increment the nonce after the lanes
+	// and shares.
+	powMinNonceTrits = powLanes128 + powShareTrits + 8
+)
+
+func init() {
+	PowProcs = runtime.NumCPU()
+	if PowProcs != 1 {
+		PowProcs--
+	}
+}
+
+// powJob is the state of the search of a nonce for one transaction.
+//
+// Curl absorbs a transaction in chunks of HashSize trits and only the last
+// one changes with the nonce, so mid is the state before the last transform
+// and only its trits of the nonce, mid[start:end], are searched. The nonce is
+// at the end of the transaction but may begin in the previous chunk, whose
+// trits are left as they are.
+//
+// If the transactions aren't hashed with Curl-P, hasher absorbed the chunks
+// before the last one, mid is the last one and the nonces are searched by
+// searchHasher.
+type powJob struct {
+	mid        Trits
+	start, end int
+	mwm        int
+	rounds     int
+	hasher     TritHasher
+
+	nonce       Trits
+	nonceOffset int
+
+	// stop and hashes are accessed atomically, also by C code
+	stop   int32
+	hashes int64
+}
+
+// newPowJobFrom returns the job of a transaction whose first trits were
+// written to h, in chunks of HashSize trits, and whose others are tr. The job
+// searches nonces for the hashes of h, which is modified.
+func newPowJobFrom(h TritHasher, tr Trits, mwm int) (*powJob, error) {
+	if mwm < 0 || mwm > HashSize {
+		return nil, ErrInvalidMWM
+	}
+	if len(tr) < NonceTrinarySize {
+		return nil, errors.New("trytes are too short to search a nonce")
+	}
+
+	last := (len(tr) - 1) / HashSize * HashSize
+	j := &powJob{
+		start:       len(tr) - NonceTrinarySize - last,
+		end:         len(tr) - last,
+		mwm:         mwm,
+		nonce:       tr[len(tr)-NonceTrinarySize:],
+		nonceOffset: len(tr) - NonceTrinarySize - last,
+	}
+	if j.start < 0 {
+		j.start = 0
+	}
+	if j.end-j.start < powMinNonceTrits {
+		return nil, fmt.Errorf("only %d trits of the nonce can be searched", j.end-j.start)
+	}
+
+	if c, ok := h.(*CurlHasher); ok && c.chunk.n == 0 {
+		c.curl.absorbTrits(tr[:last])
+		copy(c.curl.state, tr[last:])
+		j.mid = c.curl.state
+		j.rounds = c.curl.rounds
+		return j, nil
+	}
+
+	if _, err := h.Write(tr[:last]); err != nil {
+		return nil, err
+	}
+	j.hasher = h
+	j.mid = make(Trits, len(tr)-last)
+	copy(j.mid, tr[last:])
+	return j, nil
+}
+
+func (j *powJob) stopped() bool {
+	return atomic.LoadInt32(&j.stop) != 0
+}
+
+func (j *powJob) halt() {
+	atomic.StoreInt32(&j.stop, 1)
+}
+
+// result returns the nonce of the transaction made with the trits found.
+func (j *powJob) result(found Trits) Trytes {
+	nonce := make(Trits, NonceTrinarySize)
+	copy(nonce, j.nonce)
+	copy(nonce[j.start-j.nonceOffset:], found)
+	return nonce.Trytes()
+}
+
+// PowResult is the outcome of a PoW search.
+type PowResult struct {
+	Nonce    Trytes
+	Hashes   int64
+	Duration time.Duration
+}
+
+// HashRate returns the hashes tried per second.
+func (r *PowResult) HashRate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Hashes) / r.Duration.Seconds()
+}
+
+// PowEngine does PoW with one of the implementations of the package.
+// Every search has its own state, so concurrent searches are independent.
+type PowEngine struct {
+	name string
+	impl powImpl
+
+	// search searches the nonces of whole transactions instead of impl if it
+	// is not nil.
+	search func(ctx context.Context, trytes Trytes, mwm int) (Trytes, error)
+
+	// rounds is the variant of Curl-P of the hashes, that of the current
+	// hash scheme if zero.
+	rounds int
+}
+
+// NewPowEngine returns the engine of the implementation called name,
+// e.g. "PowGo" or "PowSSE".
+func NewPowEngine(name string) (*PowEngine, error) {
+	impl, ok := powImpls[name]
+	if !ok {
+		return nil, ErrUnknownPow
+	}
+	return &PowEngine{name: name, impl: impl}, nil
+}
+
+// NewPowEngineFunc returns an engine called name whose searches call search
+// with the trytes of the transaction. The hashes before the nonce aren't
+// shared between its searches, unlike those of the implementations of the
+// package.
+func NewPowEngineFunc(name string, search func(ctx context.Context, trytes Trytes, mwm int) (Trytes, error)) *PowEngine {
+	return &PowEngine{name: name, search: search}
+}
+
+// WithRounds returns an engine with the implementation of e searching nonces
+// for the hash of Curl-P with rounds rounds, e.g. CurlP27, whatever the hash
+// scheme. Only PowGo and PowC support other variants than Curl-P-81.
+func (e *PowEngine) WithRounds(rounds int) (*PowEngine, error) {
+	switch {
+	case rounds <= 0:
+		return nil, fmt.Errorf("invalid number of rounds %d", rounds)
+	case e.search != nil, rounds != numberOfRounds && !e.impl.anyRounds:
+		return nil, ErrPowRounds
+	}
+	return &PowEngine{name: e.name, impl: e.impl, rounds: rounds}, nil
+}
+
+// Rounds returns the number of rounds of the Curl-P whose nonces e searches,
+// or zero if the transactions of the current hash scheme aren't hashed with
+// Curl-P.
+func (e *PowEngine) Rounds() int {
+	if e.rounds == 0 {
+		return CurrentHashScheme().CurlRounds
+	}
+	return e.rounds
+}
+
+// hasher returns a hasher of the transactions whose nonces e searches.
+func (e *PowEngine) hasher() TritHasher {
+	if e.rounds == 0 {
+		return CurrentHashScheme().Transaction()
+	}
+	return NewCurlPHasher(e.rounds)
+}
+
+// BestPowEngine returns the engine of the most preferable implementation,
+// the fastest one if UseFastestPoW was called.
+func BestPowEngine() *PowEngine {
+	fastestPow.Lock()
+	fastest := fastestPow.name
+	fastestPow.Unlock()
+	if impl, exist := powImpls[fastest]; exist {
+		return &PowEngine{name: fastest, impl: impl}
+	}
+
+	// PowGo is the last and default return value
+	powOrderPreference := []string{"PowCL", "PowSSE", "PowCARM64", "PowC128", "PowC"}
+
+	for _, name := range powOrderPreference {
+		if impl, exist := powImpls[name]; exist {
+			return &PowEngine{name: name, impl: impl}
+		}
+	}
+	return &PowEngine{name: "PowGo", impl: powImpls["PowGo"]}
+}
+
+// GetBestPoW returns most preferable PoW func.
+func GetBestPoW() (string, PowFunc) {
+	e := BestPowEngine()
+	return e.Name(), e.PowFunc(context.Background())
+}
+
+// Name returns the name of the implementation.
+func (e *PowEngine) Name() string {
+	return e.name
+}
+
+// PowFunc returns a PowFunc searching with e until ctx is done.
+func (e *PowEngine) PowFunc(ctx context.Context) PowFunc {
+	return func(trytes Trytes, mwm int) (Trytes, error) {
+		r, err := e.Search(ctx, trytes, mwm)
+		return r.Nonce, err
+	}
+}
+
+// Search searches a nonce for the transaction trytes whose hash ends with
+// mwm zero trits. It returns the error of ctx if ctx is done first.
+//
+// If the transactions of the current hash scheme aren't hashed with Curl-P,
+// the nonces are searched in Go whatever the implementation of e.
+func (e *PowEngine) Search(ctx context.Context, trytes Trytes, mwm int) (PowResult, error) {
+	if err := trytes.IsValid(); err != nil {
+		return PowResult{}, err
+	}
+	if e.search != nil {
+		return e.searchFunc(ctx, trytes, mwm)
+	}
+	j, err := newPowJobFrom(e.hasher(), trytes.Trits(), mwm)
+	if err != nil {
+		return PowResult{}, err
+	}
+	return e.run(ctx, j)
+}
+
+// searchFunc searches a nonce with e.search.
+func (e *PowEngine) searchFunc(ctx context.Context, trytes Trytes, mwm int) (PowResult, error) {
+	start := time.Now()
+	nonce, err := e.search(ctx, trytes, mwm)
+	return PowResult{Nonce: nonce, Duration: time.Since(start)}, err
+}
+
+func (e *PowEngine) run(ctx context.Context, j *powJob) (PowResult, error) {
+	search, procs := e.impl.search, e.impl.procs
+	switch {
+	case j.hasher != nil:
+		search, procs = searchHasher, 0
+	case j.rounds != numberOfRounds && !e.impl.anyRounds:
+		return PowResult{}, ErrPowRounds
+	}
+
+	start := time.Now()
+	if procs == 0 {
+		procs = PowProcs
+	}
+	if procs < 1 {
+		procs = 1
+	}
+
+	found := make(chan Trits, procs)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for n := 0; n < procs; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if nonce := search(j, n); nonce != nil {
+				found <- nonce
+			}
+		}(n)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var nonce Trits
+	select {
+	case nonce = <-found:
+	case <-ctx.Done():
+	case <-done:
+	}
+	j.halt()
+	<-done
+
+	if nonce == nil {
+		select {
+		case nonce = <-found:
+		default:
+		}
+	}
+
+	r := PowResult{Hashes: atomic.LoadInt64(&j.hashes), Duration: time.Since(start)}
+	switch {
+	case nonce != nil:
+		r.Nonce = j.result(nonce)
+		return r, nil
+	case ctx.Err() != nil:
+		return r, ctx.Err()
+	}
+	return r, ErrNonceNotFound
+}
+
+// searchHasher is the powSearch of the jobs of a hasher. Every try clones
+// j.hasher and hashes the last chunk with a nonce, whose first trits are n.
+func searchHasher(j *powJob, n int) Trits {
+	chunk := make(Trits, len(j.mid))
+	copy(chunk, j.mid)
+	nonce := chunk[j.start:j.end]
+	copy(nonce, Int2Trits(int64(n), powShareTrits))
+
+	for !j.stopped() {
+		incTrits(nonce[powShareTrits:])
+		h := j.hasher.Clone()
+		h.Write(chunk)
+		atomic.AddInt64(&j.hashes, 1)
+		if h.Sum(nil).TrailingZeros() >= int64(j.mwm) {
+			return nonce
+		}
+	}
+	return nil
+}
+
+// searchPow searches a nonce with the implementation called name.
+func searchPow(name string, trytes Trytes, mwm int) (Trytes, error) {
+	e, err := NewPowEngine(name)
+	if err != nil {
+		return "", err
+	}
+	r, err := e.Search(context.Background(), trytes, mwm)
+	return r.Nonce, err
+}
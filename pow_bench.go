@@ -0,0 +1,215 @@
+package giota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPowBenchmarkMWMs are the MWMs BenchmarkPoW searches nonces with by
+// default.
+var DefaultPowBenchmarkMWMs = []int{6, 9, 12}
+
+// PowBenchmark is the measured speed of a PoW implementation.
+type PowBenchmark struct {
+	Name string
+	MWMs []PowMWMBenchmark
+}
+
+// HashRate returns the hashes tried per second over all the MWMs.
+func (b *PowBenchmark) HashRate() float64 {
+	var r PowResult
+	for _, m := range b.MWMs {
+		r.Hashes += m.Hashes
+		r.Duration += m.Duration
+	}
+	return r.HashRate()
+}
+
+// PowMWMBenchmark is the measured speed of a PoW implementation for a MWM.
+type PowMWMBenchmark struct {
+	MWM int
+
+	// Hashes were tried during Duration, including by the search cancelled
+	// at its end.
+	Hashes   int64
+	Duration time.Duration
+
+	// Nonces are the times to find a nonce, sorted.
+	Nonces []time.Duration
+}
+
+// HashRate returns the hashes tried per second.
+func (b *PowMWMBenchmark) HashRate() float64 {
+	r := PowResult{Hashes: b.Hashes, Duration: b.Duration}
+	return r.HashRate()
+}
+
+// Percentile returns the time to find a nonce which the fraction p of the
+// searches didn't exceed, or zero if no nonce was found.
+func (b *PowMWMBenchmark) Percentile(p float64) time.Duration {
+	if len(b.Nonces) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(b.Nonces))+0.5) - 1
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(b.Nonces):
+		i = len(b.Nonces) - 1
+	}
+	return b.Nonces[i]
+}
+
+// BenchmarkPoW runs every PoW implementation for d, divided between the
+// mwms, DefaultPowBenchmarkMWMs if none is given. The benchmarks are
+// returned from the fastest to the slowest.
+func BenchmarkPoW(d time.Duration, mwms ...int) []PowBenchmark {
+	if len(mwms) == 0 {
+		mwms = DefaultPowBenchmarkMWMs
+	}
+
+	names := powImplNames()
+	bs := make([]PowBenchmark, 0, len(names))
+	for _, name := range names {
+		e := &PowEngine{name: name, impl: powImpls[name]}
+		b := PowBenchmark{Name: name}
+		for _, mwm := range mwms {
+			b.MWMs = append(b.MWMs, benchmarkMWM(e, mwm, d/time.Duration(len(mwms))))
+		}
+		bs = append(bs, b)
+	}
+
+	sort.SliceStable(bs, func(i, j int) bool {
+		return bs[i].HashRate() > bs[j].HashRate()
+	})
+	return bs
+}
+
+// benchmarkMWM searches nonces of synthetic transactions with e for d.
+func benchmarkMWM(e *PowEngine, mwm int, d time.Duration) PowMWMBenchmark {
+	b := PowMWMBenchmark{MWM: mwm}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	tx := Transaction{
+		Address:           Address(EmptyHash),
+		Timestamp:         time.Unix(1500000000, 0),
+		Bundle:            EmptyHash,
+		TrunkTransaction:  EmptyHash,
+		BranchTransaction: EmptyHash,
+	}
+	for i := int64(0); ctx.Err() == nil; i++ {
+		tx.CurrentIndex = i
+		r, err := e.Search(ctx, tx.Trytes(), mwm)
+		b.Hashes += r.Hashes
+		b.Duration += r.Duration
+		if err == nil {
+			b.Nonces = append(b.Nonces, r.Duration)
+		}
+	}
+
+	sort.Slice(b.Nonces, func(i, j int) bool { return b.Nonces[i] < b.Nonces[j] })
+	return b
+}
+
+func powImplNames() []string {
+	names := make([]string, 0, len(powImpls))
+	for name := range powImpls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fastestPow is the name of the implementation chosen by UseFastestPoW.
+var fastestPow struct {
+	sync.Mutex
+	name string
+}
+
+// powBenchCache is the result of UseFastestPoW saved in a file. It is valid
+// on the same architecture and number of CPUs with the same implementations.
+type powBenchCache struct {
+	Arch    string   `json:"arch"`
+	CPUs    int      `json:"cpus"`
+	Impls   []string `json:"impls"`
+	Fastest string   `json:"fastest"`
+}
+
+func newPowBenchCache(fastest string) *powBenchCache {
+	return &powBenchCache{
+		Arch:    runtime.GOARCH,
+		CPUs:    runtime.NumCPU(),
+		Impls:   powImplNames(),
+		Fastest: fastest,
+	}
+}
+
+func (c *powBenchCache) valid() bool {
+	cur := newPowBenchCache(c.Fastest)
+	if c.Arch != cur.Arch || c.CPUs != cur.CPUs || len(c.Impls) != len(cur.Impls) {
+		return false
+	}
+	for i := range c.Impls {
+		if c.Impls[i] != cur.Impls[i] {
+			return false
+		}
+	}
+	_, ok := powImpls[c.Fastest]
+	return ok
+}
+
+// WritePowBenchmark saves the fastest implementation of bs in the file
+// cacheFile for UseFastestPoW.
+func WritePowBenchmark(cacheFile string, bs []PowBenchmark) error {
+	if len(bs) == 0 {
+		return errors.New("no PoW benchmarks to save")
+	}
+	b, err := json.Marshal(newPowBenchCache(bs[0].Name))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cacheFile, b, 0644)
+}
+
+// UseFastestPoW makes BestPowEngine and GetBestPoW return the implementation
+// measured to be the fastest on this machine instead of following a fixed
+// order of preference, and returns its name.
+// The result saved in cacheFile is used if it is still valid. Otherwise the
+// implementations are benchmarked for d and, if cacheFile is not empty, the
+// result is saved in it.
+func UseFastestPoW(d time.Duration, cacheFile string) (string, error) {
+	if cacheFile != "" {
+		if b, err := ioutil.ReadFile(cacheFile); err == nil {
+			var c powBenchCache
+			if json.Unmarshal(b, &c) == nil && c.valid() {
+				setFastestPow(c.Fastest)
+				return c.Fastest, nil
+			}
+		}
+	}
+
+	bs := BenchmarkPoW(d)
+	if len(bs) == 0 {
+		return "", ErrUnknownPow
+	}
+	if cacheFile != "" {
+		if err := WritePowBenchmark(cacheFile, bs); err != nil {
+			return "", err
+		}
+	}
+	setFastestPow(bs[0].Name)
+	return bs[0].Name, nil
+}
+
+func setFastestPow(name string) {
+	fastestPow.Lock()
+	fastestPow.name = name
+	fastestPow.Unlock()
+}
@@ -0,0 +1,58 @@
+package giota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkPoW(t *testing.T) {
+	bs := BenchmarkPoW(200*time.Millisecond, 3, 6)
+	if len(bs) != len(powImpls) {
+		t.Fatalf("%d implementations were benchmarked, want %d", len(bs), len(powImpls))
+	}
+
+	for i, b := range bs {
+		if len(b.MWMs) != 2 || b.MWMs[0].MWM != 3 || b.MWMs[1].MWM != 6 {
+			t.Errorf("%s was not benchmarked with every MWM", b.Name)
+		}
+		if len(b.MWMs[0].Nonces) == 0 || b.MWMs[0].Percentile(0.5) <= 0 {
+			t.Errorf("%s found no nonce with MWM 3", b.Name)
+		}
+		if i > 0 && b.HashRate() > bs[i-1].HashRate() {
+			t.Error("benchmarks are not sorted by hash rate")
+		}
+	}
+}
+
+func TestUseFastestPoW(t *testing.T) {
+	defer setFastestPow("")
+
+	dir, err := ioutil.TempDir("", "giota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "powbench.json")
+
+	name, err := UseFastestPoW(100*time.Millisecond, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if BestPowEngine().Name() != name {
+		t.Errorf("BestPowEngine() is %s, not the fastest %s", BestPowEngine().Name(), name)
+	}
+
+	// the cached result is used instead of benchmarking again
+	if err = WritePowBenchmark(cache, []PowBenchmark{{Name: "PowGo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if name, err = UseFastestPoW(time.Hour, cache); err != nil || name != "PowGo" {
+		t.Errorf("UseFastestPoW() with a cache returned %s, %v", name, err)
+	}
+	if n, _ := GetBestPoW(); n != "PowGo" {
+		t.Errorf("GetBestPoW() returned %s", n)
+	}
+}
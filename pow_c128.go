@@ -36,10 +36,9 @@ package giota
 #define HBITS 0xFFFFFFFFFFFFFFFFuLL
 #define LBITS 0x0000000000000000uLL
 #define HASH_LENGTH 243              //trits
-#define NONCE_LENGTH 81              //trits
 #define STATE_LENGTH 3 * HASH_LENGTH //trits
-#define TX_LENGTH 2673               //trytes
-#define INCR_START HASH_LENGTH - NONCE_LENGTH + 4 + 27
+#define LANES 5
+#define SHARE_LENGTH 27
 
 #define LOW00 0xDB6DB6DB6DB6DB6DuLL  //0b1101101101101101101101101101101101101101101101101101101101101101L;
 #define HIGH00 0xB6DB6DB6DB6DB6DBuLL //0b1011011011011011011011011011011011011011011011011011011011011011L;
@@ -134,24 +133,22 @@ void transformC128(unsigned __int128 *lmid, unsigned __int128 *hmid)
   }
 }
 
-int incrC128(unsigned __int128 *mid_low, unsigned __int128 *mid_high)
+int incrC128(unsigned __int128 *mid_low, unsigned __int128 *mid_high, int from, int to)
 {
   int i;
-  unsigned __int128 carry;
+  unsigned __int128 carry = set_int128_2x64(HBITS, HBITS);
 
-  carry = set_int128_2x64(LOW00, LOW01);
-
-  for (i = INCR_START; i < HASH_LENGTH && (i == INCR_START || low64(&carry)); i++)
+  for (i = from; i < to && low64(&carry); i++)
   {
     unsigned __int128 low = mid_low[i], high = mid_high[i];
     mid_low[i] = high ^ low;
     mid_high[i] = low;
     carry = high & (~low);
   }
-  return i == HASH_LENGTH;
+  return i == to;
 }
 
-void seriC128(unsigned __int128 *low, unsigned __int128 *high, int n, signed char *r)
+void seriC128(unsigned __int128 *low, unsigned __int128 *high, int n, signed char *r, int from, int to)
 {
   int i = 0, index = 0;
 
@@ -161,24 +158,24 @@ void seriC128(unsigned __int128 *low, unsigned __int128 *high, int n, signed cha
     index = 1;
   }
 
-  for (i = HASH_LENGTH-NONCE_LENGTH; i < HASH_LENGTH; i++)
+  for (i = from; i < to; i++)
   {
     unsigned long long ll = (get64(&low[i], index) >> n) & 1;
     unsigned long long hh = (get64(&high[i], index) >> n) & 1;
 
     if (hh == 0 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = -1;
+      r[i - from] = -1;
     }
 
     if (hh == 1 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 0;
+      r[i - from] = 0;
     }
 
     if (hh == 1 && ll == 0)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 1;
+      r[i - from] = 1;
     }
   }
 }
@@ -212,15 +209,12 @@ int checkC128(unsigned __int128 *l, unsigned __int128 *h, int m)
   return -2;
 }
 
-int stopC128 = 1;
-
-long long int loopC128(unsigned __int128 *lmid, unsigned __int128 *hmid, int m, signed char *nonce)
+int loopC128(unsigned __int128 *lmid, unsigned __int128 *hmid, int m, signed char *nonce, int start, int end, int *stop, long long *hashes)
 {
   int n = 0, j = 0;
-  long long int i = 0;
   unsigned __int128 lcpy[STATE_LENGTH * 2], hcpy[STATE_LENGTH * 2];
 
-  for (i = 0; !incrC128(lmid, hmid) && !stopC128; i++)
+  while (!incrC128(lmid, hmid, start + LANES + SHARE_LENGTH, end) && !__atomic_load_n(stop, __ATOMIC_RELAXED))
   {
     for (j = 0; j < STATE_LENGTH; j++)
     {
@@ -228,14 +222,15 @@ long long int loopC128(unsigned __int128 *lmid, unsigned __int128 *hmid, int m,
       hcpy[j] = hmid[j];
     }
     transformC128(lcpy, hcpy);
+    __atomic_fetch_add(hashes, 128, __ATOMIC_RELAXED);
 
     if ((n = checkC128(lcpy + STATE_LENGTH, hcpy + STATE_LENGTH, m)) >= 0)
     {
-      seriC128(lmid, hmid, n, nonce);
-      return i * 128;
+      seriC128(lmid, hmid, n, nonce, start, end);
+      return 1;
     }
   }
-  return -i*128-1;
+  return 0;
 }
 
 void paraC128(signed char in[], unsigned __int128 l[], unsigned __int128 h[])
@@ -255,111 +250,62 @@ void paraC128(signed char in[], unsigned __int128 l[], unsigned __int128 h[])
       break;
     case -1:
       l[i] = set_int128_2x64(HBITS, HBITS);
-      h[i] = set_int128_2x64(LBITS, LBITS);      
+      h[i] = set_int128_2x64(LBITS, LBITS);
       break;
     }
   }
 }
 
-void incrNC128(int n,unsigned __int128 *mid_low, unsigned __int128 *mid_high)
-{
-  int i,j;
-  for (j=0;j<n;j++){
-    unsigned __int128 carry;
-    carry = set_int128_2x64(HBITS, HBITS);
-    for (i = HASH_LENGTH * 2/3 + 4; i < HASH_LENGTH * 2/3 + 4 + 27 && low64(&carry); i++)
-    {
-      unsigned __int128 low = mid_low[i], high = mid_high[i];
-      mid_low[i] = high ^ low;
-      mid_high[i] = low;
-      carry = high & (~low);
-    }
-  }
-}
-
-long long int pworkC128(signed char mid[], int mwm, signed char nonce[], int n)
+// pworkC128 searches the nth share of the nonces in the trits [start, end) of
+// mid and returns 1 if one is found.
+int pworkC128(signed char mid[], int mwm, signed char nonce[], int n, int start, int end, int *stop, long long *hashes)
 {
+  int j;
   unsigned __int128 lmid[STATE_LENGTH], hmid[STATE_LENGTH];
   paraC128(mid, lmid, hmid);
-  int offset = HASH_LENGTH - NONCE_LENGTH;
- 
-  lmid[offset] = set_int128_2x64(LOW00, LOW01);
-  hmid[offset] = set_int128_2x64(HIGH00, HIGH01);
-  lmid[offset+1] = set_int128_2x64(LOW10, LOW11);
-  hmid[offset+1] = set_int128_2x64(HIGH10, HIGH11);
-  lmid[offset+2] = set_int128_2x64(LOW20, LOW21);
-  hmid[offset+2] = set_int128_2x64(HIGH20, HIGH21);
-  lmid[offset+3] = set_int128_2x64(LOW30, LOW31);
-  hmid[offset+3] = set_int128_2x64(HIGH30, HIGH31);
-  lmid[offset+4] = set_int128_2x64(LOW40, LOW41);
-  hmid[offset+4] = set_int128_2x64(HIGH40, HIGH41);
-  
-  incrNC128(n, lmid, hmid);
-  return loopC128(lmid, hmid, mwm, nonce);
+
+  lmid[start] = set_int128_2x64(LOW00, LOW01);
+  hmid[start] = set_int128_2x64(HIGH00, HIGH01);
+  lmid[start + 1] = set_int128_2x64(LOW10, LOW11);
+  hmid[start + 1] = set_int128_2x64(HIGH10, HIGH11);
+  lmid[start + 2] = set_int128_2x64(LOW20, LOW21);
+  hmid[start + 2] = set_int128_2x64(HIGH20, HIGH21);
+  lmid[start + 3] = set_int128_2x64(LOW30, LOW31);
+  hmid[start + 3] = set_int128_2x64(HIGH30, HIGH31);
+  lmid[start + 4] = set_int128_2x64(LOW40, LOW41);
+  hmid[start + 4] = set_int128_2x64(HIGH40, HIGH41);
+
+  for (j = 0; j < n; j++)
+  {
+    incrC128(lmid, hmid, start + LANES, start + LANES + SHARE_LENGTH);
+  }
+  return loopC128(lmid, hmid, mwm, nonce, start, end, stop, hashes);
 }
 */
 import "C"
 import (
-	"errors"
-	"sync"
 	"unsafe"
 )
 
 func init() {
-	powFuncs["PowC128"] = PowC128
+	powImpls["PowC128"] = powImpl{search: searchC128}
 }
 
-var countC128 int64
+func searchC128(j *powJob, n int) Trits {
+	nonce := make(Trits, j.end-j.start)
+
+	// nolint: gas
+	r := C.pworkC128((*C.schar)(unsafe.Pointer(&j.mid[0])), C.int(j.mwm),
+		(*C.schar)(unsafe.Pointer(&nonce[0])), C.int(n), C.int(j.start), C.int(j.end),
+		(*C.int)(unsafe.Pointer(&j.stop)), (*C.longlong)(unsafe.Pointer(&j.hashes)))
+	if r == 0 {
+		return nil
+	}
+	return nonce
+}
 
 // PowC128 is a proof of work library for Iota that uses the standard __int128 C type that is available in 64 bit processors (AMD64 and ARM64).
 // This PoW calculator follows common C standards and does not rely on SSE which is AMD64 specific.
 func PowC128(trytes Trytes, mwm int) (Trytes, error) {
-	if C.stopC128 == 0 {
-		C.stopC128 = 1
-		return "", errors.New("pow is already running, stopped")
-	}
-
-	if trytes == "" {
-		return "", errors.New("invalid trytes")
-	}
-
-	C.stopC128 = 0
-	countC128 = 0
-	c := NewCurl()
-	c.Absorb(trytes[:(transactionTrinarySize-HashSize)/3])
-	tr := trytes.Trits()
-	copy(c.state, tr[transactionTrinarySize-HashSize:])
-
-	var (
-		result Trytes
-		wg     sync.WaitGroup
-		mutex  sync.Mutex
-	)
-
-	for n := 0; n < PowProcs; n++ {
-		wg.Add(1)
-		go func(n int) {
-			nonce := make(Trits, NonceTrinarySize)
-
-			// nolint: gas
-			r := C.pworkC128((*C.schar)(unsafe.Pointer(&c.state[0])), C.int(mwm), (*C.schar)(unsafe.Pointer(&nonce[0])), C.int(n))
-			mutex.Lock()
-
-			switch {
-			case r >= 0:
-				result = nonce.Trytes()
-				C.stopC128 = 1
-				countC128 += int64(r)
-			default:
-				countC128 += int64(-r + 1)
-			}
-
-			mutex.Unlock()
-			wg.Done()
-		}(n)
-	}
-
-	wg.Wait()
-	C.stopC128 = 1
-	return result, nil
+	return searchPow("PowC128", trytes, mwm)
 }
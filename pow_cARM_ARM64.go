@@ -37,10 +37,9 @@ package giota
 #define HBITS 0xFFFFFFFFFFFFFFFFuLL
 #define LBITS 0x0000000000000000uLL
 #define HASH_LENGTH 243              //trits
-#define NONCE_LENGTH 81              //trits
 #define STATE_LENGTH 3 * HASH_LENGTH //trits
-#define TX_LENGTH 2673               //trytes
-#define INCR_START HASH_LENGTH - NONCE_LENGTH + 4 + 27
+#define LANES 5
+#define SHARE_LENGTH 27
 
 #define LOW00 0xDB6DB6DB6DB6DB6DuLL  //0b1101101101101101101101101101101101101101101101101101101101101101L;
 #define HIGH00 0xB6DB6DB6DB6DB6DBuLL //0b1011011011011011011011011011011011011011011011011011011011011011L;
@@ -109,23 +108,23 @@ void transformARM64(uint64x2_t *lmid, uint64x2_t *hmid)
   }
 }
 
-int incrARM64(uint64x2_t *mid_low, uint64x2_t *mid_high)
+int incrARM64(uint64x2_t *mid_low, uint64x2_t *mid_high, int from, int to)
 {
   int i;
 
-  uint64x2_t carry = {LOW00, LOW01};
+  uint64x2_t carry = {HBITS, HBITS};
 
-  for (i = INCR_START; i < HASH_LENGTH && (i == INCR_START || carry[0]); i++)
+  for (i = from; i < to && carry[0]; i++)
   {
     uint64x2_t low = mid_low[i], high = mid_high[i];
     mid_low[i] = high ^ low;
     mid_high[i] = low;
     carry = high & (~low);
   }
-  return i == HASH_LENGTH;
+  return i == to;
 }
 
-void seriARM64(uint64x2_t *low, uint64x2_t *high, int n, signed char *r)
+void seriARM64(uint64x2_t *low, uint64x2_t *high, int n, signed char *r, int from, int to)
 {
   int i = 0, index = 0;
   if (n > 63)
@@ -133,21 +132,21 @@ void seriARM64(uint64x2_t *low, uint64x2_t *high, int n, signed char *r)
     n -= 64;
     index = 1;
   }
-  for (i = HASH_LENGTH-NONCE_LENGTH; i < HASH_LENGTH; i++)
+  for (i = from; i < to; i++)
   {
     unsigned long long ll = (low[i][index] >> n) & 1;
     unsigned long long hh = (high[i][index] >> n) & 1;
     if (hh == 0 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = -1;
+      r[i - from] = -1;
     }
     if (hh == 1 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 0;
+      r[i - from] = 0;
     }
     if (hh == 1 && ll == 0)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 1;
+      r[i - from] = 1;
     }
   }
 }
@@ -179,15 +178,12 @@ int checkARM64(uint64x2_t *l, uint64x2_t *h, int m)
   return -2;
 }
 
-int stop_ArmNEON=1;
-
-long long int loopARM64(uint64x2_t *lmid, uint64x2_t *hmid, int m, signed char *nonce)
+int loopARM64(uint64x2_t *lmid, uint64x2_t *hmid, int m, signed char *nonce, int start, int end, int *stop, long long *hashes)
 {
   int n = 0, j = 0;
-  long long int i = 0;
 
   uint64x2_t lcpy[STATE_LENGTH * 2], hcpy[STATE_LENGTH * 2];
-  for (i = 0; !incrARM64(lmid, hmid) && !stop_ArmNEON; i++)
+  while (!incrARM64(lmid, hmid, start + LANES + SHARE_LENGTH, end) && !__atomic_load_n(stop, __ATOMIC_RELAXED))
   {
     for (j = 0; j < STATE_LENGTH; j++)
     {
@@ -195,13 +191,14 @@ long long int loopARM64(uint64x2_t *lmid, uint64x2_t *hmid, int m, signed char *
       hcpy[j] = hmid[j];
     }
     transformARM64(lcpy, hcpy);
+    __atomic_fetch_add(hashes, 128, __ATOMIC_RELAXED);
     if ((n = checkARM64(lcpy + STATE_LENGTH, hcpy + STATE_LENGTH, m)) >= 0)
     {
-      seriARM64(lmid, hmid, n, nonce);
-      return i * 128;
+      seriARM64(lmid, hmid, n, nonce, start, end);
+      return 1;
     }
   }
-  return -i*128-1;
+  return 0;
 }
 
 void paraARM64(signed char in[], uint64x2_t l[], uint64x2_t h[])
@@ -233,125 +230,70 @@ void paraARM64(signed char in[], uint64x2_t l[], uint64x2_t h[])
   }
 }
 
-void incrNARM64(int n, uint64x2_t *mid_low, uint64x2_t *mid_high)
-{
-  int i,j;
-  for (j=0;j<n;j++){
-    uint64x2_t carry = {HBITS, HBITS};
-
-    for (i = HASH_LENGTH * 2/3 + 4; i < HASH_LENGTH * 2/3 + 4 + 27 &&  carry[0]; i++)
-    {
-      uint64x2_t low = mid_low[i], high = mid_high[i];
-      mid_low[i] = high ^ low;
-      mid_high[i] = low;
-      carry = high & (~low);
-    }
-  }
-}
-
-long long int pworkARM64(signed char mid[], int mwm, signed char nonce[], int n)
+// pworkARM64 searches the nth share of the nonces in the trits [start, end)
+// of mid and returns 1 if one is found.
+int pworkARM64(signed char mid[], int mwm, signed char nonce[], int n, int start, int end, int *stop, long long *hashes)
 {
+  int j;
   uint64x2_t lmid[STATE_LENGTH], hmid[STATE_LENGTH];
 
   paraARM64(mid, lmid, hmid);
-  int offset = HASH_LENGTH - NONCE_LENGTH;
-
-  lmid[offset][0] = LOW00;
-  lmid[offset][1] = LOW01;
-  hmid[offset][0] = HIGH00;
-  hmid[offset][1] = HIGH01;
 
-  lmid[offset+1][0] = LOW10;
-  lmid[offset+1][1] = LOW11;
-  hmid[offset+1][0] = HIGH10;
-  hmid[offset+1][1] = HIGH11;
+  lmid[start][0] = LOW00;
+  lmid[start][1] = LOW01;
+  hmid[start][0] = HIGH00;
+  hmid[start][1] = HIGH01;
 
-  lmid[offset+2][0] = LOW20;
-  lmid[offset+2][1] = LOW21;
-  hmid[offset+2][0] = HIGH20;
-  hmid[offset+2][1] = HIGH21;
+  lmid[start + 1][0] = LOW10;
+  lmid[start + 1][1] = LOW11;
+  hmid[start + 1][0] = HIGH10;
+  hmid[start + 1][1] = HIGH11;
 
-  lmid[offset+3][0] = LOW30;
-  lmid[offset+3][1] = LOW31;
-  hmid[offset+3][0] = HIGH30;
-  hmid[offset+3][1] = HIGH31;
+  lmid[start + 2][0] = LOW20;
+  lmid[start + 2][1] = LOW21;
+  hmid[start + 2][0] = HIGH20;
+  hmid[start + 2][1] = HIGH21;
 
-  lmid[offset+4][0] = LOW40;
-  lmid[offset+4][1] = LOW41;
-  hmid[offset+4][0] = HIGH40;
-  hmid[offset+4][1] = HIGH41;
+  lmid[start + 3][0] = LOW30;
+  lmid[start + 3][1] = LOW31;
+  hmid[start + 3][0] = HIGH30;
+  hmid[start + 3][1] = HIGH31;
 
-  incrNARM64(n, lmid, hmid);
+  lmid[start + 4][0] = LOW40;
+  lmid[start + 4][1] = LOW41;
+  hmid[start + 4][0] = HIGH40;
+  hmid[start + 4][1] = HIGH41;
 
-  return loopARM64(lmid, hmid, mwm, nonce);
+  for (j = 0; j < n; j++)
+  {
+    incrARM64(lmid, hmid, start + LANES, start + LANES + SHARE_LENGTH);
+  }
+  return loopARM64(lmid, hmid, mwm, nonce, start, end, stop, hashes);
 }
 */
 import "C"
 import (
-	"errors"
-	"sync"
 	"unsafe"
-  "fmt"
 )
 
 func init() {
-	powFuncs["PowCARM64"] = PowCARM64
+	powImpls["PowCARM64"] = powImpl{search: searchCARM64}
 }
 
-var countC128 int64
-
-// PowC128 is a proof of work library for Iota that uses the standard __int128 C type that is available in 64 bit processors (AMD64 and ARM64).
-// This PoW calculator follows common C standards and does not rely on SSE which is AMD64 specific.
-func PowCARM64(trytes Trytes, mwm int) (Trytes, error) {
-	if C.stopCARM64 == 0 {
-		C.stopCARM64 = 1
-		return "", errors.New("pow is already running, stopped")
-	}
+func searchCARM64(j *powJob, n int) Trits {
+	nonce := make(Trits, j.end-j.start)
 
-	if trytes == "" {
-		return "", errors.New("invalid trytes")
-	}
-
-	C.stopCARM64 = 0
-	countCARM64 = 0
-	c := NewCurl()
-	c.Absorb(trytes[:(transactionTrinarySize-HashSize)/3])
-	tr := trytes.Trits()
-	copy(c.state, tr[transactionTrinarySize-HashSize:])
-
-	var (
-		result Trytes
-		wg     sync.WaitGroup
-		mutex  sync.Mutex
-	)
-
-	for n := 0; n < PowProcs; n++ {
-		wg.Add(1)
-		go func(n int) {
-			nonce := make(Trits, NonceTrinarySize)
-
-			// nolint: gas
-			r := C.pworkCARM64((*C.schar)(unsafe.Pointer(&c.state[0])), C.int(mwm), (*C.schar)(unsafe.Pointer(&nonce[0])), C.int(n))
-			mutex.Lock()
-
-      fmt.Printf("r: %d\n", r)
-      fmt.Printf("nonce len: %d\n", len(nonce))
-
-			switch {
-			case r >= 0:
-				result = nonce.Trytes()
-				C.stopCARM64 = 1
-				countCARM64 += int64(r)
-			default:
-				countCARM64 += int64(-r + 1)
-			}
-
-			mutex.Unlock()
-			wg.Done()
-		}(n)
+	// nolint: gas
+	r := C.pworkARM64((*C.schar)(unsafe.Pointer(&j.mid[0])), C.int(j.mwm),
+		(*C.schar)(unsafe.Pointer(&nonce[0])), C.int(n), C.int(j.start), C.int(j.end),
+		(*C.int)(unsafe.Pointer(&j.stop)), (*C.longlong)(unsafe.Pointer(&j.hashes)))
+	if r == 0 {
+		return nil
 	}
+	return nonce
+}
 
-	wg.Wait()
-	C.stopCARM64 = 1
-	return result, nil
+// PowCARM64 is proof of work of iota for arm64 using NEON.
+func PowCARM64(trytes Trytes, mwm int) (Trytes, error) {
+	return searchPow("PowCARM64", trytes, mwm)
 }
@@ -30,14 +30,13 @@ import (
 	"encoding/binary"
 	"errors"
 	"sync/atomic"
+	"time"
 
 	"github.com/iotaledger/giota/cl"
 )
 
 var loopcount byte = 32
 
-var countCL int64
-
 type bufferInfo struct {
 	size    int64
 	flag    cl.MemFlag
@@ -46,20 +45,19 @@ type bufferInfo struct {
 }
 
 func init() {
-	// TODO: update to Curl-P-81
-	// pows["PowCL"] = PowCL
+	// TODO: update to Curl-P-81 and to nonces beginning before the last chunk
+	// powImpls["PowCL"] = powImpl{search: searchCL, procs: 1}
 }
 
-var stopCL = true
-
 // nolint: gocyclo
 func exec(
 	que *cl.CommandQueue,
 	ker []*cl.Kernel,
 	cores, nlocal int,
 	mobj []*cl.MemObject,
+	j *powJob,
 	founded *int32,
-	tryte chan Trytes) error {
+	tryte chan Trits) error {
 
 	// initialize
 	nglobal := cores * nlocal
@@ -76,7 +74,7 @@ func exec(
 	found := make([]byte, 1)
 	var cnt int
 	num := int64(cores) * 64 * int64(loopcount)
-	for cnt = 0; found[0] == 0 && *founded == 0 && !stopCL; cnt++ {
+	for cnt = 0; found[0] == 0 && atomic.LoadInt32(founded) == 0 && !j.stopped(); cnt++ {
 		// start searching
 		var ev2 *cl.Event
 		ev2, err = que.EnqueueNDRangeKernel(ker[1], nil, []int{nglobal}, []int{nlocal}, nil)
@@ -91,15 +89,13 @@ func exec(
 		}
 		ev3.Release()
 
-		atomic.AddInt64(&countCL, num)
+		atomic.AddInt64(&j.hashes, num)
 	}
 
-	if *founded != 0 || stopCL {
+	if !atomic.CompareAndSwapInt32(founded, 0, 1) || j.stopped() {
 		return nil
 	}
 
-	atomic.StoreInt32(founded, 1)
-
 	// finalize, get the result.
 	ev4, err := que.EnqueueNDRangeKernel(ker[2], nil, []int{nglobal}, []int{nlocal}, nil)
 	if err != nil {
@@ -126,12 +122,12 @@ func exec(
 		}
 	}
 
-	tryte <- rr.Trytes()
+	tryte <- rr[j.start:j.end]
 	return nil
 }
 
 // nolint: gocyclo
-func loopCL(binfo []bufferInfo) (Trytes, error) {
+func loopCL(j *powJob, binfo []bufferInfo) (Trits, error) {
 	defers := make([]func(), 0, 10)
 	defer func() {
 		for _, f := range defers {
@@ -141,12 +137,12 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 
 	platforms, err := cl.GetPlatforms()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	exist := false
 	var founded int32
-	result := make(chan Trytes)
+	result := make(chan Trits, 1)
 	for _, p := range platforms {
 		var devs []*cl.Device
 		devs, err = p.GetDevices(cl.DeviceTypeGPU)
@@ -161,18 +157,18 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 		cont, err := cl.CreateContext(devs)
 		defers = append(defers, cont.Release)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		prog, err := cont.CreateProgramWithSource([]string{kernel})
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		defers = append(defers, prog.Release)
 		if err := prog.BuildProgram(devs, "-Werror"); err != nil {
 			println(p.Name())
-			return "", err
+			return nil, err
 		}
 
 		ker := make([]*cl.Kernel, 3)
@@ -187,7 +183,7 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 		for i, n := range []string{"init", "search", "finalize"} {
 			ker[i], err = prog.CreateKernel(n)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 		}
 
@@ -213,7 +209,7 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 
 			que, err := cont.CreateCommandQueue(d, 0)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 
 			defers = append(defers, que.Release)
@@ -225,12 +221,12 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 				}
 
 				if totalmem += msize; totalmem > mmax {
-					return "", errors.New("max memory passed")
+					return nil, errors.New("max memory passed")
 				}
 
 				mobj[i], err = cont.CreateEmptyBuffer(inf.flag, int(msize))
 				if err != nil {
-					return "", err
+					return nil, err
 				}
 
 				if inf.data != nil {
@@ -247,20 +243,20 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 					}
 
 					if err != nil {
-						return "", err
+						return nil, err
 					}
 					ev.Release()
 				}
 
 				for _, k := range ker {
 					if err := k.SetArg(i, mobj[i]); err != nil {
-						return "", err
+						return nil, err
 					}
 				}
 			}
 
 			go func() {
-				err := exec(que, ker, cores, nlocal, mobj, &founded, result)
+				err := exec(que, ker, cores, nlocal, mobj, j, &founded, result)
 				if err != nil {
 					panic(err)
 				}
@@ -269,33 +265,26 @@ func loopCL(binfo []bufferInfo) (Trytes, error) {
 	}
 
 	if !exist {
-		return "", errors.New("no GPU found")
+		return nil, errors.New("no GPU found")
 	}
 
-	r := <-result
-	close(result)
-
-	return r, nil
-}
-
-// PowCL is proof of work of iota in OpenCL.
-func PowCL(trytes Trytes, mwm int) (Trytes, error) {
-	switch {
-	case !stopCL:
-		stopCL = true
-		return "", errors.New("pow is already running, stopped")
-	case trytes == "":
-		return "", errors.New("invalid trytes")
+	// the searches of the devices end once one finds a nonce or j is
+	// stopped
+	for {
+		select {
+		case r := <-result:
+			j.halt()
+			return r, nil
+		case <-time.After(100 * time.Millisecond):
+			if j.stopped() {
+				return nil, nil
+			}
+		}
 	}
+}
 
-	stopCL = false
-	countCL = 0
-	c := NewCurl()
-	c.Absorb(trytes[:(transactionTrinarySize-HashSize)/3])
-	tr := trytes.Trits()
-	copy(c.state, tr[transactionTrinarySize-HashSize:])
-
-	lmid, hmid := para(c.state)
+func searchCL(j *powJob, n int) Trits {
+	lmid, hmid := para(j.mid)
 	lmid[0] = low0
 	hmid[0] = high0
 	lmid[1] = low1
@@ -332,7 +321,7 @@ func PowCL(trytes Trytes, mwm int) (Trytes, error) {
 			8 * stateSize, cl.MemReadWrite, true, nil,
 		},
 		bufferInfo{
-			8, cl.MemWriteOnly, false, []byte{byte(mwm), 0, 0, 0, 0, 0, 0, 0}, // mwm
+			8, cl.MemWriteOnly, false, []byte{byte(j.mwm), 0, 0, 0, 0, 0, 0, 0}, // mwm
 		},
 		bufferInfo{
 			1, cl.MemReadWrite, false, nil,
@@ -345,5 +334,14 @@ func PowCL(trytes Trytes, mwm int) (Trytes, error) {
 		},
 	}
 
-	return loopCL(binfo)
+	r, err := loopCL(j, binfo)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// PowCL is proof of work of iota in OpenCL.
+func PowCL(trytes Trytes, mwm int) (Trytes, error) {
+	return searchPow("PowCL", trytes, mwm)
 }
@@ -25,9 +25,7 @@ SOFTWARE.
 package giota
 
 import (
-	"errors"
-	"runtime"
-	"sync"
+	"sync/atomic"
 )
 
 // trytes
@@ -43,52 +41,21 @@ const (
 	high2 uint64 = 0xFFC01FFFF803FFFF
 	low3  uint64 = 0xFFC0000007FFFFFF
 	high3 uint64 = 0x003FFFFFFFFFFFFF
-
-	nonceOffset         = HashSize - NonceTrinarySize
-	nonceInitStart      = nonceOffset + 4
-	nonceIncrementStart = nonceInitStart + NonceTrinarySize/3
-)
-
-// PowFunc is the func type for PoW
-type PowFunc func(Trytes, int) (Trytes, error)
-
-var (
-	powFuncs = make(map[string]PowFunc)
-	// PowProcs is number of concurrent processes (default is NumCPU()-1)
-	PowProcs int
 )
 
 func init() {
-	powFuncs["PowGo"] = PowGo
-	PowProcs = runtime.NumCPU()
-	if PowProcs != 1 {
-		PowProcs--
-	}
-}
-
-// GetBestPoW returns most preferable PoW func.
-func GetBestPoW() (string, PowFunc) {
-
-	// PowGo is the last and default return value
-	powOrderPreference := []string{"PowCL", "PowSSE", "PowCARM64", "PowC128", "PowC"}
-
-	for _, pow := range powOrderPreference {
-		if p, exist := powFuncs[pow]; exist {
-			return pow, p
-		}
-	}
-
-	return "PowGo", PowGo // default return PowGo if no others
+	powImpls["PowGo"] = powImpl{search: searchGo, anyRounds: true}
 }
 
-func transform64(lmid *[stateSize]uint64, hmid *[stateSize]uint64) {
+// transform64 transforms 64 bit-sliced states in rounds of Curl-P.
+func transform64(lmid *[stateSize]uint64, hmid *[stateSize]uint64, rounds int) {
 	var ltmp, htmp [stateSize]uint64
 	lfrom := lmid
 	hfrom := hmid
 	lto := &ltmp
 	hto := &htmp
 
-	for r := 0; r < numberOfRounds-1; r++ {
+	for r := 0; r < rounds; r++ {
 		for j := 0; j < stateSize; j++ {
 			t1 := indices[j]
 			t2 := indices[j+1]
@@ -106,49 +73,40 @@ func transform64(lmid *[stateSize]uint64, hmid *[stateSize]uint64) {
 		hfrom, hto = hto, hfrom
 	}
 
-	for j := 0; j < stateSize; j++ {
-		t1, t2 := indices[j], indices[j+1]
-
-		alpha := lfrom[t1]
-		beta := hfrom[t1]
-		gamma := hfrom[t2]
-		delta := (alpha | (^gamma)) & (lfrom[t2] ^ beta)
-
-		lto[j] = ^delta
-		hto[j] = (alpha ^ gamma) | delta
+	if lfrom != lmid {
+		copy(lmid[:], lfrom[:])
+		copy(hmid[:], hfrom[:])
 	}
-
-	copy(lmid[:], ltmp[:])
-	copy(hmid[:], htmp[:])
 }
 
-func incr(lmid *[stateSize]uint64, hmid *[stateSize]uint64) bool {
+// incr increments the nonce in the trits [from, to) and returns true when
+// it overflows.
+func incr(lmid *[stateSize]uint64, hmid *[stateSize]uint64, from, to int) bool {
 	var carry uint64 = 1
 	var i int
-	//to avoid boundry check, I believe.
-	for i = nonceInitStart; i < HashSize && carry != 0; i++ {
+	for i = from; i < to && carry != 0; i++ {
 		low := lmid[i]
 		high := hmid[i]
 		lmid[i] = high ^ low
 		hmid[i] = low
 		carry = high & (^low)
 	}
-	return i == HashSize
+	return i == to
 }
 
-func seri(l *[stateSize]uint64, h *[stateSize]uint64, n uint) Trits {
-	r := make(Trits, NonceTrinarySize)
-	for i := nonceOffset; i < HashSize; i++ {
+func seri(l *[stateSize]uint64, h *[stateSize]uint64, n uint, from, to int) Trits {
+	r := make(Trits, to-from)
+	for i := from; i < to; i++ {
 		ll := (l[i] >> n) & 1
 		hh := (h[i] >> n) & 1
 
 		switch {
 		case hh == 0 && ll == 1:
-			r[i-nonceOffset] = -1
+			r[i-from] = -1
 		case hh == 1 && ll == 1:
-			r[i-nonceOffset] = 0
+			r[i-from] = 0
 		case hh == 1 && ll == 0:
-			r[i-nonceOffset] = 1
+			r[i-from] = 1
 		}
 	}
 	return r
@@ -172,22 +130,20 @@ func check(l *[stateSize]uint64, h *[stateSize]uint64, m int) int {
 	return -1
 }
 
-var stopGO = true
-
-func loop(lmid *[stateSize]uint64, hmid *[stateSize]uint64, m int) (Trits, int64) {
+func loop(j *powJob, lmid *[stateSize]uint64, hmid *[stateSize]uint64) Trits {
 	var lcpy, hcpy [stateSize]uint64
-	var i int64
-	for i = 0; !incr(lmid, hmid) && !stopGO; i++ {
+	from := j.start + powLanes64 + powShareTrits
+	for !incr(lmid, hmid, from, j.end) && !j.stopped() {
 		copy(lcpy[:], lmid[:])
 		copy(hcpy[:], hmid[:])
-		transform64(&lcpy, &hcpy)
+		transform64(&lcpy, &hcpy, j.rounds)
+		atomic.AddInt64(&j.hashes, 64)
 
-		if n := check(&lcpy, &hcpy, m); n >= 0 {
-			nonce := seri(lmid, hmid, uint(n))
-			return nonce, i * 64
+		if n := check(&lcpy, &hcpy, j.mwm); n >= 0 {
+			return seri(lmid, hmid, uint(n), j.start, j.end)
 		}
 	}
-	return nil, i * 64
+	return nil
 }
 
 // 01:-1 11:0 10:1
@@ -210,77 +166,26 @@ func para(in Trits) (*[stateSize]uint64, *[stateSize]uint64) {
 	return &l, &h
 }
 
-func incrN(n int, lmid *[stateSize]uint64, hmid *[stateSize]uint64) {
-	for j := 0; j < n; j++ {
-		var carry uint64 = 1
-
-		// to avoid boundry check, i believe.
-		for i := nonceInitStart; i < nonceIncrementStart && carry != 0; i++ {
-			low := lmid[i]
-			high := hmid[i]
-			lmid[i] = high ^ low
-			hmid[i] = low
-			carry = high & (^low)
-		}
+func searchGo(j *powJob, n int) Trits {
+	lmid, hmid := para(j.mid)
+	s := j.start
+	lmid[s] = low0
+	hmid[s] = high0
+	lmid[s+1] = low1
+	hmid[s+1] = high1
+	lmid[s+2] = low2
+	hmid[s+2] = high2
+	lmid[s+3] = low3
+	hmid[s+3] = high3
+
+	from := s + powLanes64
+	for i := 0; i < n; i++ {
+		incr(lmid, hmid, from, from+powShareTrits)
 	}
+	return loop(j, lmid, hmid)
 }
 
-var countGo int64 = 1
-
 // PowGo is proof of work for iota in pure Go
 func PowGo(trytes Trytes, mwm int) (Trytes, error) {
-	if !stopGO {
-		stopGO = true
-		return "", errors.New("pow is already running, stopped")
-	}
-
-	if trytes == "" {
-		return "", errors.New("invalid trytes")
-	}
-
-	countGo = 0
-	stopGO = false
-
-	c := NewCurl()
-	c.Absorb(trytes[:(transactionTrinarySize-HashSize)/3])
-	tr := trytes.Trits()
-	copy(c.state, tr[transactionTrinarySize-HashSize:])
-
-	var (
-		result Trytes
-		wg     sync.WaitGroup
-		mutex  sync.Mutex
-	)
-
-	for i := 0; i < PowProcs; i++ {
-		wg.Add(1)
-		go func(i int) {
-			lmid, hmid := para(c.state)
-			lmid[nonceOffset] = low0
-			hmid[nonceOffset] = high0
-			lmid[nonceOffset+1] = low1
-			hmid[nonceOffset+1] = high1
-			lmid[nonceOffset+2] = low2
-			hmid[nonceOffset+2] = high2
-			lmid[nonceOffset+3] = low3
-			hmid[nonceOffset+3] = high3
-
-			incrN(i, lmid, hmid)
-			nonce, cnt := loop(lmid, hmid, mwm)
-
-			mutex.Lock()
-			if nonce != nil {
-				result = nonce.Trytes()
-				stopGO = true
-			}
-
-			countGo += cnt
-			mutex.Unlock()
-			wg.Done()
-		}(i)
-	}
-
-	wg.Wait()
-	stopGO = true
-	return result, nil
+	return searchPow("PowGo", trytes, mwm)
 }
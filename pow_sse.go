@@ -41,10 +41,9 @@ package giota
 #define HBITS 0xFFFFFFFFFFFFFFFFuLL
 #define LBITS 0x0000000000000000uLL
 #define HASH_LENGTH 243              //trits
-#define NONCE_LENGTH 81              //trits
 #define STATE_LENGTH 3 * HASH_LENGTH //trits
-#define TX_LENGTH 2673               //trytes
-#define INCR_START HASH_LENGTH - NONCE_LENGTH + 4 + 27
+#define LANES 5
+#define SHARE_LENGTH 27
 
 #define LOW00 0xDB6DB6DB6DB6DB6DuLL  //0b1101101101101101101101101101101101101101101101101101101101101101L;
 #define HIGH00 0xB6DB6DB6DB6DB6DBuLL //0b1011011011011011011011011011011011011011011011011011011011011011L;
@@ -113,22 +112,21 @@ void transform128(__m128i *lmid, __m128i *hmid)
   }
 }
 
-int incr128(__m128i *mid_low, __m128i *mid_high)
+int incr128(__m128i *mid_low, __m128i *mid_high, int from, int to)
 {
   int i;
-  __m128i carry;
-  carry = _mm_set_epi64x(LOW00, LOW01);
-  for (i = INCR_START; i < HASH_LENGTH && (i == INCR_START || carry[0]); i++)
+  __m128i carry = _mm_set_epi64x(HBITS, HBITS);
+  for (i = from; i < to && carry[0]; i++)
   {
     __m128i low = mid_low[i], high = mid_high[i];
     mid_low[i] = high ^ low;
     mid_high[i] = low;
     carry = high & (~low);
   }
-  return i == HASH_LENGTH;
+  return i == to;
 }
 
-void seri128(__m128i *low, __m128i *high, int n, char *r)
+void seri128(__m128i *low, __m128i *high, int n, char *r, int from, int to)
 {
   int i = 0, index = 0;
   if (n > 63)
@@ -136,21 +134,21 @@ void seri128(__m128i *low, __m128i *high, int n, char *r)
     n -= 64;
     index = 1;
   }
-  for (i = HASH_LENGTH-NONCE_LENGTH; i < HASH_LENGTH; i++)
+  for (i = from; i < to; i++)
   {
     unsigned long long ll = (low[i][index] >> n) & 1;
     unsigned long long hh = (high[i][index] >> n) & 1;
     if (hh == 0 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = -1;
+      r[i - from] = -1;
     }
     if (hh == 1 && ll == 1)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 0;
+      r[i - from] = 0;
     }
     if (hh == 1 && ll == 0)
     {
-      r[i+NONCE_LENGTH-HASH_LENGTH] = 1;
+      r[i - from] = 1;
     }
   }
 }
@@ -181,15 +179,12 @@ int check128(__m128i *l, __m128i *h, int m)
   return -2;
 }
 
-int stopSSE=1;
-
-long long int loop128(__m128i *lmid, __m128i *hmid, int m, char *nonce)
+int loop128(__m128i *lmid, __m128i *hmid, int m, char *nonce, int start, int end, int *stop, long long *hashes)
 {
   int n = 0, j = 0;
-  long long int i = 0;
 
   __m128i lcpy[STATE_LENGTH * 2], hcpy[STATE_LENGTH * 2];
-  for (i = 0; !incr128(lmid, hmid) && !stopSSE; i++)
+  while (!incr128(lmid, hmid, start + LANES + SHARE_LENGTH, end) && !__atomic_load_n(stop, __ATOMIC_RELAXED))
   {
     for (j = 0; j < STATE_LENGTH; j++)
     {
@@ -197,13 +192,14 @@ long long int loop128(__m128i *lmid, __m128i *hmid, int m, char *nonce)
       hcpy[j] = hmid[j];
     }
     transform128(lcpy, hcpy);
+    __atomic_fetch_add(hashes, 128, __ATOMIC_RELAXED);
     if ((n = check128(lcpy + STATE_LENGTH, hcpy + STATE_LENGTH, m)) >= 0)
     {
-      seri128(lmid, hmid, n, nonce);
-      return i * 128;
+      seri128(lmid, hmid, n, nonce, start, end);
+      return 1;
     }
   }
-  return -i*128-1;
+  return 0;
 }
 
 // 01:-1 11:0 10:1
@@ -230,106 +226,55 @@ void para128(char in[], __m128i l[], __m128i h[])
   }
 }
 
-void incrN128(int n,__m128i *mid_low, __m128i *mid_high)
-{
-  int i,j;
-  for (j=0;j<n;j++){
-    __m128i carry;
-    carry = _mm_set_epi64x(HBITS, HBITS);
-    for (i = HASH_LENGTH * 2/3 + 4; i < HASH_LENGTH * 2/3 + 4 + 27 &&  carry[0]; i++)
-    {
-      __m128i low = mid_low[i], high = mid_high[i];
-      mid_low[i] = high ^ low;
-      mid_high[i] = low;
-      carry = high & (~low);
-    }
-  }
-}
-
-long long int pwork128(char mid[], int mwm, char nonce[],int n)
+// pwork128 searches the nth share of the nonces in the trits [start, end) of
+// mid and returns 1 if one is found.
+int pwork128(char mid[], int mwm, char nonce[], int n, int start, int end, int *stop, long long *hashes)
 {
+  int j;
   __m128i lmid[STATE_LENGTH], hmid[STATE_LENGTH];
 
   para128(mid, lmid, hmid);
-  int offset = HASH_LENGTH - NONCE_LENGTH;
-  lmid[offset] = _mm_set_epi64x(LOW00, LOW01);
-  hmid[offset] = _mm_set_epi64x(HIGH00, HIGH01);
-  lmid[offset+1] = _mm_set_epi64x(LOW10, LOW11);
-  hmid[offset+1] = _mm_set_epi64x(HIGH10, HIGH11);
-  lmid[offset+2] = _mm_set_epi64x(LOW20, LOW21);
-  hmid[offset+2] = _mm_set_epi64x(HIGH20, HIGH21);
-  lmid[offset+3] = _mm_set_epi64x(LOW30, LOW31);
-  hmid[offset+3] = _mm_set_epi64x(HIGH30, HIGH31);
-  lmid[offset+4] = _mm_set_epi64x(LOW40, LOW41);
-  hmid[offset+4] = _mm_set_epi64x(HIGH40, HIGH41);
-
-	incrN128(n, lmid, hmid);
-  return loop128(lmid, hmid, mwm, nonce);
+  lmid[start] = _mm_set_epi64x(LOW00, LOW01);
+  hmid[start] = _mm_set_epi64x(HIGH00, HIGH01);
+  lmid[start + 1] = _mm_set_epi64x(LOW10, LOW11);
+  hmid[start + 1] = _mm_set_epi64x(HIGH10, HIGH11);
+  lmid[start + 2] = _mm_set_epi64x(LOW20, LOW21);
+  hmid[start + 2] = _mm_set_epi64x(HIGH20, HIGH21);
+  lmid[start + 3] = _mm_set_epi64x(LOW30, LOW31);
+  hmid[start + 3] = _mm_set_epi64x(HIGH30, HIGH31);
+  lmid[start + 4] = _mm_set_epi64x(LOW40, LOW41);
+  hmid[start + 4] = _mm_set_epi64x(HIGH40, HIGH41);
+
+  for (j = 0; j < n; j++)
+  {
+    incr128(lmid, hmid, start + LANES, start + LANES + SHARE_LENGTH);
+  }
+  return loop128(lmid, hmid, mwm, nonce, start, end, stop, hashes);
 }
 */
 import "C"
 import (
-	"errors"
-	"sync"
 	"unsafe"
 )
 
 func init() {
-	powFuncs["PowSSE"] = PowSSE
+	powImpls["PowSSE"] = powImpl{search: searchSSE}
 }
 
-var countSSE int64
+func searchSSE(j *powJob, n int) Trits {
+	nonce := make(Trits, j.end-j.start)
 
-// PowSSE is proof of work for iota for amd64 using SSE2(or AMD64).
-func PowSSE(trytes Trytes, mwm int) (Trytes, error) {
-	if C.stopSSE == 0 {
-		C.stopSSE = 1
-		return "", errors.New("pow is already running, stopped")
-	}
-
-	if trytes == "" {
-		return "", errors.New("invalid trytes")
-	}
-
-	C.stopSSE = 0
-	countSSE = 0
-	c := NewCurl()
-	c.Absorb(trytes[:(transactionTrinarySize-HashSize)/3])
-	tr := trytes.Trits()
-	copy(c.state, tr[transactionTrinarySize-HashSize:])
-
-	var (
-		result Trytes
-		wg     sync.WaitGroup
-		mutex  sync.Mutex
-	)
-
-	for n := 0; n < PowProcs; n++ {
-		wg.Add(1)
-		go func(n int) {
-			nonce := make(Trits, NonceTrinarySize)
-
-			// nolint: gas
-			r := C.pwork128((*C.char)(
-				unsafe.Pointer(&c.state[0])), C.int(mwm), (*C.char)(unsafe.Pointer(&nonce[0])), C.int(n))
-
-			mutex.Lock()
-
-			switch {
-			case r >= 0:
-				result = nonce.Trytes()
-				C.stopSSE = 1
-				countSSE += int64(r)
-			default:
-				countSSE += int64(-r + 1)
-			}
-
-			mutex.Unlock()
-			wg.Done()
-		}(n)
+	// nolint: gas
+	r := C.pwork128((*C.char)(unsafe.Pointer(&j.mid[0])), C.int(j.mwm),
+		(*C.char)(unsafe.Pointer(&nonce[0])), C.int(n), C.int(j.start), C.int(j.end),
+		(*C.int)(unsafe.Pointer(&j.stop)), (*C.longlong)(unsafe.Pointer(&j.hashes)))
+	if r == 0 {
+		return nil
 	}
+	return nonce
+}
 
-	wg.Wait()
-	C.stopSSE = 1
-	return result, nil
+// PowSSE is proof of work for iota for amd64 using SSE2(or AMD64).
+func PowSSE(trytes Trytes, mwm int) (Trytes, error) {
+	return searchPow("PowSSE", trytes, mwm)
 }
@@ -0,0 +1,144 @@
+package giota
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPowTransaction returns a transaction whose nonce begins in the chunk
+// before the last one absorbed by Curl.
+func testPowTransaction() Transaction {
+	return Transaction{
+		Address:           Address(strings.Repeat("A", 81)),
+		Timestamp:         time.Unix(1500000000, 0),
+		Bundle:            Trytes(strings.Repeat("B", 81)),
+		TrunkTransaction:  EmptyHash,
+		BranchTransaction: EmptyHash,
+		Tag:               "POW",
+	}
+}
+
+// testPow searches a nonce with the implementation called name, checks it
+// and returns the hash rate in kH/sec.
+func testPow(t *testing.T, name string, mwm int) float64 {
+	e, err := NewPowEngine(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := testPowTransaction()
+	r, err := e.Search(context.Background(), tx.Trytes(), mwm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Nonce = r.Nonce
+	if !tx.HasValidNonce(int64(mwm)) {
+		t.Error("pow is illegal", tx.Hash())
+	}
+	if r.Hashes <= 0 {
+		t.Error("no hashes were reported")
+	}
+	return r.HashRate() / 1000
+}
+
+func TestPowEngineConcurrentSearches(t *testing.T) {
+	e := BestPowEngine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			tx := testPowTransaction()
+			tx.CurrentIndex = int64(i)
+			r, err := e.Search(context.Background(), tx.Trytes(), 9)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tx.Nonce = r.Nonce
+			if !tx.HasValidNonce(9) {
+				t.Errorf("search %d returned an invalid nonce", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPowEngineCancel(t *testing.T) {
+	e, err := NewPowEngine("PowGo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	tx := testPowTransaction()
+	r, err := e.Search(ctx, tx.Trytes(), 60)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Search() returned %v after its context was done", err)
+	}
+	if r.Hashes <= 0 {
+		t.Error("no hashes were reported")
+	}
+}
+
+func TestPowEngineErrors(t *testing.T) {
+	if _, err := NewPowEngine("PowFoo"); err != ErrUnknownPow {
+		t.Errorf("NewPowEngine() of an unknown implementation returned %v", err)
+	}
+
+	e := BestPowEngine()
+	tx := testPowTransaction()
+	if _, err := e.Search(context.Background(), tx.Trytes(), HashSize+1); err != ErrInvalidMWM {
+		t.Errorf("Search() with an invalid mwm returned %v", err)
+	}
+	if _, err := e.Search(context.Background(), "ABC", 1); err == nil {
+		t.Error("Search() of short trytes succeeded")
+	}
+}
+
+func TestPowEngineRounds(t *testing.T) {
+	for name, impl := range powImpls {
+		e, err := NewPowEngine(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Rounds() != CurlP81 {
+			t.Errorf("%s: engine has %d rounds", name, e.Rounds())
+		}
+
+		e, err = e.WithRounds(CurlP27)
+		if !impl.anyRounds {
+			if err != ErrPowRounds {
+				t.Errorf("%s: WithRounds() returned %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx := testPowTransaction()
+		r, err := e.Search(context.Background(), tx.Trytes(), 12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Nonce = r.Nonce
+		c := NewCurlP(CurlP27)
+		c.Absorb(tx.Trytes())
+		if n := c.Squeeze().Trits().TrailingZeros(); n < 12 {
+			t.Errorf("%s: Curl-P-27 hash has %d trailing zeros", name, n)
+		}
+	}
+
+	if _, err := BestPowEngine().WithRounds(0); err == nil {
+		t.Error("WithRounds(0) succeeded")
+	}
+}
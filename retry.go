@@ -0,0 +1,137 @@
+package giota
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NetworkError is returned when a command could not be sent to the node or its
+// response could not be read.
+type NetworkError struct {
+	Command string
+	Err     error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Err)
+}
+
+// sent returns false if the error happened before the request reached the node.
+func (e *NetworkError) sent() bool {
+	ue, ok := e.Err.(*url.Error)
+	if !ok {
+		return true
+	}
+	oe, ok := ue.Err.(*net.OpError)
+	return !ok || oe.Op != "dial"
+}
+
+// NodeError is an error reported by the node in an ErrorResponse, or an HTTP
+// status other than 200 without one.
+type NodeError struct {
+	Command    string
+	StatusCode int
+	Message    string
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Message)
+}
+
+// Temporary returns true if the node may succeed when the command is retried,
+// i.e. on server errors and while the node is not synced.
+func (e *NodeError) Temporary() bool {
+	msg := strings.ToLower(e.Message)
+	return e.StatusCode >= 500 ||
+		strings.Contains(msg, "not synced") ||
+		strings.Contains(msg, "subtangle has not been updated")
+}
+
+// DecodeError is returned when the response of the node can't be decoded.
+type DecodeError struct {
+	Command string
+	Body    []byte
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: cannot decode response: %s", e.Command, e.Err)
+}
+
+// nonIdempotent lists the commands which must not be sent twice. They are only
+// retried if the previous attempt surely did not reach the node: a second
+// attachToTangle would redo the PoW while the node may still be busy with the first.
+var nonIdempotent = map[string]bool{
+	"attachToTangle": true,
+}
+
+// IsIdempotent returns true if command can safely be sent more than once.
+func IsIdempotent(command string) bool {
+	return !nonIdempotent[command]
+}
+
+// RetryPolicy controls how API retries commands which failed with a transient error.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a command is sent.
+	// Values below 1 mean 1.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor applied to the delay after each attempt.
+	Multiplier float64
+
+	// Jitter is the fraction of the delay which is randomized, between 0 and 1.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the RetryPolicy of APIs returned by NewAPI.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// NoRetry sends every command once.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// Retryable returns true if command may be sent again after it failed with err.
+func (p RetryPolicy) Retryable(command string, err error) bool {
+	switch e := err.(type) {
+	case *NetworkError:
+		return IsIdempotent(command) || !e.sent()
+	case *NodeError:
+		return IsIdempotent(command) && e.Temporary()
+	}
+	return false
+}
+
+// Backoff returns the delay before the next attempt after attempt failed.
+// attempt starts at 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d *= 1 - p.Jitter + 2*p.Jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,150 @@
+package giota
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	Multiplier:     2,
+}
+
+// flakyServer fails the first fails requests with status and a body.
+func flakyServer(fails int32, status int, body string) (*httptest.Server, *int32) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= fails {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		w.Write([]byte(`{"hashes":[]}`))
+	}))
+	return ts, &calls
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		fails  int32
+		status int
+		body   string
+		call   func(api *API) error
+		calls  int32
+		ok     bool
+	}{
+		{
+			name: "server error is retried", fails: 2, status: http.StatusInternalServerError,
+			call:  func(api *API) error { _, err := api.GetTips(); return err },
+			calls: 3, ok: true,
+		},
+		{
+			name: "not synced is retried", fails: 1, status: http.StatusBadRequest,
+			body:  `{"error":"This operations cannot be executed: The subtangle has not been updated yet."}`,
+			call:  func(api *API) error { _, err := api.GetTips(); return err },
+			calls: 2, ok: true,
+		},
+		{
+			name: "bad request is not retried", fails: 1, status: http.StatusBadRequest,
+			body:  `{"error":"invalid parameters"}`,
+			call:  func(api *API) error { _, err := api.GetTips(); return err },
+			calls: 1,
+		},
+		{
+			name: "attempts are limited", fails: 5, status: http.StatusServiceUnavailable,
+			call:  func(api *API) error { return api.BroadcastTransactions(nil) },
+			calls: 3,
+		},
+		{
+			name: "attachToTangle is not retried", fails: 1, status: http.StatusInternalServerError,
+			call: func(api *API) error {
+				_, err := api.AttachToTangle(&AttachToTangleRequest{})
+				return err
+			},
+			calls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		ts, calls := flakyServer(tt.fails, tt.status, tt.body)
+		api := NewAPI(ts.URL, nil)
+		api.SetRetryPolicy(testRetryPolicy)
+
+		err := tt.call(api)
+		ts.Close()
+
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if *calls != tt.calls {
+			t.Errorf("%s: node was called %d times, expected %d", tt.name, *calls, tt.calls)
+		}
+		if err != nil {
+			if _, ok := err.(*NodeError); !ok {
+				t.Errorf("%s: expected a *NodeError but got %T", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestRetryErrorTypes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hashes":`))
+	}))
+	api := NewAPI(ts.URL, nil)
+	api.SetRetryPolicy(NoRetry)
+
+	if _, err := api.GetTips(); err == nil {
+		t.Error("GetTips() expected a decode error")
+	} else if _, ok := err.(*DecodeError); !ok {
+		t.Errorf("GetTips() expected a *DecodeError but got %T", err)
+	}
+
+	ts.Close()
+	if _, err := api.GetTips(); err == nil {
+		t.Error("GetTips() expected a network error")
+	} else if ne, ok := err.(*NetworkError); !ok {
+		t.Errorf("GetTips() expected a *NetworkError but got %T", err)
+	} else if ne.sent() {
+		t.Error("request to a closed server is reported as sent")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{6, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			d := p.Backoff(tt.attempt)
+			if d < tt.base/2 || d > tt.base*3/2 {
+				t.Errorf("Backoff(%d) = %s, expected %s with 50%% jitter", tt.attempt, d, tt.base)
+			}
+		}
+	}
+}
+
+func TestNodeErrorMessage(t *testing.T) {
+	err := &NodeError{Command: "getBalances", StatusCode: http.StatusBadRequest, Message: "invalid address"}
+	if s := err.Error(); s != "getBalances: invalid address" {
+		t.Errorf("Error() = %q", s)
+	}
+}
@@ -0,0 +1,170 @@
+package giota
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses after which
+// ScanAddresses stops.
+const DefaultGapLimit = 20
+
+// ErrInvalidGapLimit is returned by ScanAddresses if the gap limit is not positive.
+var ErrInvalidGapLimit = errors.New("gap limit must be positive")
+
+// AddressStatus is the activity of an address found by ScanAddresses.
+type AddressStatus struct {
+	Index   int
+	Address Address
+
+	// Used is true if the address has transactions in the tangle.
+	Used bool
+
+	// SpentFrom is true if the address is an input of a bundle.
+	SpentFrom bool
+
+	// Balance is the confirmed balance of the address.
+	Balance int64
+
+	// LastActivity is the latest timestamp of the transactions of the address.
+	LastActivity time.Time
+}
+
+// AddressReport is the result of ScanAddresses.
+type AddressReport struct {
+	// Addresses are all scanned addresses in index order, from index 0 up to
+	// and including the gap after the last used address.
+	Addresses []AddressStatus
+
+	// Balances are the decoded non-zero balances of the scanned addresses.
+	Balances Balances
+}
+
+func (r *AddressReport) filter(fn func(s *AddressStatus) bool) []Address {
+	var adrs []Address
+	for i := range r.Addresses {
+		if fn(&r.Addresses[i]) {
+			adrs = append(adrs, r.Addresses[i].Address)
+		}
+	}
+	return adrs
+}
+
+// Used returns the addresses which have transactions.
+func (r *AddressReport) Used() []Address {
+	return r.filter(func(s *AddressStatus) bool { return s.Used })
+}
+
+// Unused returns the addresses which have no transactions.
+func (r *AddressReport) Unused() []Address {
+	return r.filter(func(s *AddressStatus) bool { return !s.Used })
+}
+
+// SpentFrom returns the addresses which must not receive value anymore.
+func (r *AddressReport) SpentFrom() []Address {
+	return r.filter(func(s *AddressStatus) bool { return s.SpentFrom })
+}
+
+// NextUnused returns the first unused address after the last used one.
+func (r *AddressReport) NextUnused() AddressStatus {
+	next := r.Addresses[0]
+	for i := len(r.Addresses) - 1; i >= 0 && !r.Addresses[i].Used; i-- {
+		next = r.Addresses[i]
+	}
+	return next
+}
+
+// Total returns the total balance of the scanned addresses.
+func (r *AddressReport) Total() int64 {
+	return r.Balances.Total()
+}
+
+// ScanAddresses restores the addresses of a keyring. It derives gapLimit
+// addresses at a time, looks them up in bulk and stops after gapLimit
+// consecutive unused addresses.
+func ScanAddresses(api NodeAPI, k *Keyring, gapLimit int) (*AddressReport, error) {
+	if gapLimit <= 0 {
+		return nil, ErrInvalidGapLimit
+	}
+
+	report := &AddressReport{}
+	gap := 0
+	for index := 0; gap < gapLimit; index += gapLimit {
+		adrs, err := k.Addresses(index, gapLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses := make(map[Address]*AddressStatus, len(adrs))
+		batch := make([]AddressStatus, len(adrs))
+		for i, adr := range adrs {
+			batch[i] = AddressStatus{Index: index + i, Address: adr}
+			statuses[adr] = &batch[i]
+		}
+
+		if err = scanActivity(api, adrs, statuses); err != nil {
+			return nil, err
+		}
+
+		for _, s := range batch {
+			report.Addresses = append(report.Addresses, s)
+			if s.Used {
+				gap = 0
+				continue
+			}
+
+			if gap++; gap >= gapLimit {
+				break
+			}
+		}
+	}
+
+	// the balances of the used addresses are decoded by their index, so all
+	// addresses from index 0 are queried
+	adrs := make([]Address, len(report.Addresses))
+	for i := range report.Addresses {
+		adrs[i] = report.Addresses[i].Address
+	}
+
+	bals, err := api.Balances(adrs, k.Seed())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range bals {
+		if b.Index < len(report.Addresses) {
+			report.Addresses[b.Index].Balance = b.Value
+		}
+	}
+	report.Balances = bals
+	return report, nil
+}
+
+// scanActivity fills the activity of adrs from their transactions.
+func scanActivity(api NodeAPI, adrs []Address, statuses map[Address]*AddressStatus) error {
+	ft, err := api.FindTransactions(&FindTransactionsRequest{Addresses: adrs})
+	if err != nil || len(ft.Hashes) == 0 {
+		return err
+	}
+
+	gt, err := api.GetTrytes(ft.Hashes)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range gt.Trytes {
+		s, ok := statuses[tx.Address]
+		if !ok {
+			continue
+		}
+
+		s.Used = true
+		if len(tx.RangeProof) < 6 || tx.RangeProof[0:6] == "999999" {
+			s.SpentFrom = true
+		}
+		if tx.Timestamp.After(s.LastActivity) {
+			s.LastActivity = tx.Timestamp
+		}
+	}
+	return nil
+}
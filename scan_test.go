@@ -0,0 +1,147 @@
+package giota
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// scanMockAPI is a NodeAPI holding some transactions by their hash.
+type scanMockAPI struct {
+	NodeAPI
+	txs      map[Trytes]Transaction
+	balances map[Address]int64
+	queried  int
+}
+
+func (m *scanMockAPI) FindTransactions(ft *FindTransactionsRequest) (*FindTransactionsResponse, error) {
+	adrs := make(map[Address]bool)
+	for _, a := range ft.Addresses {
+		adrs[a] = true
+	}
+	bundles := make(map[Trytes]bool)
+	for _, b := range ft.Bundles {
+		bundles[b] = true
+	}
+	m.queried += len(ft.Addresses)
+
+	resp := &FindTransactionsResponse{}
+	for h, tx := range m.txs {
+		if adrs[tx.Address] || bundles[tx.Bundle] {
+			resp.Hashes = append(resp.Hashes, h)
+		}
+	}
+	return resp, nil
+}
+
+func (m *scanMockAPI) GetTrytes(hashes []Trytes) (*GetTrytesResponse, error) {
+	resp := &GetTrytesResponse{}
+	for _, h := range hashes {
+		tx, ok := m.txs[h]
+		if !ok {
+			tx = Transaction{Bundle: EmptyHash}
+		}
+		resp.Trytes = append(resp.Trytes, tx)
+	}
+	return resp, nil
+}
+
+func (m *scanMockAPI) Balances(adrs []Address, seed Trytes) (Balances, error) {
+	var bals Balances
+	for i, a := range adrs {
+		if v := m.balances[a]; v > 0 {
+			bals = append(bals, Balance{Index: i, Value: v})
+		}
+	}
+	return bals, nil
+}
+
+func TestScanAddresses(t *testing.T) {
+	k, err := NewKeyring(Trytes(strings.Repeat("S", 81)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adrs, err := k.Addresses(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := strings.Repeat("A", 6)
+	input := strings.Repeat("9", 6)
+	api := &scanMockAPI{
+		txs: map[Trytes]Transaction{
+			"TX0": {Address: adrs[0], RangeProof: Trytes(output), Timestamp: time.Unix(100, 0)},
+			"TX1": {Address: adrs[1], RangeProof: Trytes(output), Timestamp: time.Unix(200, 0)},
+			"TX2": {Address: adrs[1], RangeProof: Trytes(input), Timestamp: time.Unix(300, 0)},
+			"TX3": {Address: adrs[4], RangeProof: Trytes(output), Timestamp: time.Unix(400, 0)},
+			// beyond the gap limit
+			"TX4": {Address: adrs[9], RangeProof: Trytes(output), Timestamp: time.Unix(500, 0)},
+		},
+		balances: map[Address]int64{adrs[0]: 10, adrs[4]: 5},
+	}
+
+	report, err := ScanAddresses(api, k, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// addresses 5, 6 and 7 make the gap
+	if len(report.Addresses) != 8 {
+		t.Fatalf("ScanAddresses() scanned %d addresses, expected 8", len(report.Addresses))
+	}
+	if api.queried != 9 {
+		t.Errorf("ScanAddresses() queried %d addresses, expected 9", api.queried)
+	}
+
+	for i, s := range report.Addresses {
+		if s.Index != i || s.Address != adrs[i] {
+			t.Errorf("address %d is out of order", i)
+		}
+	}
+
+	if used := report.Used(); len(used) != 3 || used[0] != adrs[0] || used[1] != adrs[1] || used[2] != adrs[4] {
+		t.Errorf("Used() = %v", used)
+	}
+	if spent := report.SpentFrom(); len(spent) != 1 || spent[0] != adrs[1] {
+		t.Errorf("SpentFrom() = %v", spent)
+	}
+	if len(report.Unused()) != 5 {
+		t.Errorf("Unused() returned %d addresses, expected 5", len(report.Unused()))
+	}
+	if next := report.NextUnused(); next.Index != 5 {
+		t.Errorf("NextUnused() = %d, expected 5", next.Index)
+	}
+	if s := report.Addresses[1]; !s.LastActivity.Equal(time.Unix(300, 0)) {
+		t.Errorf("LastActivity = %s, expected the latest transaction", s.LastActivity)
+	}
+	if report.Addresses[4].Balance != 5 || report.Total() != 15 {
+		t.Errorf("balances were not reported, total is %d", report.Total())
+	}
+
+	if _, err := ScanAddresses(api, k, 0); err != ErrInvalidGapLimit {
+		t.Errorf("ScanAddresses() expected ErrInvalidGapLimit but got %v", err)
+	}
+}
+
+func TestSecKeysOfSeveralSeeds(t *testing.T) {
+	for _, seed := range []Trytes{Trytes(strings.Repeat("S", 81)), testMessageSeed, Trytes(strings.Repeat("S", 81))} {
+		k, err := NewKeyring(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := k.SecKey(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := NewSecKey(seed, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSk, _ := want.SecretKey()
+		sk, _ := key.SecretKey()
+		if *sk != *wantSk {
+			t.Errorf("NewSecKey() of seed %s used the key of another seed", seed[:9])
+		}
+	}
+}
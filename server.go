@@ -0,0 +1,366 @@
+package giota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// APIVersion is the value of the X-IOTA-API-Version header sent by API.
+const APIVersion = "1"
+
+// ErrInvalidAPIVersion is returned to requests without an X-IOTA-API-Version header.
+var ErrInvalidAPIVersion = errors.New("Invalid API Version")
+
+// MaxBundleLength is the number of transactions of the largest bundle a
+// Server accepts in a request.
+const MaxBundleLength = 1000
+
+// requestOverhead is the room left in requests for the JSON besides the
+// transactions.
+const requestOverhead = 4096
+
+// maxRequestSize returns the size of the largest request body, an
+// attachToTangle of MaxBundleLength transactions of the largest layout.
+func maxRequestSize() int64 {
+	sizes := layoutSizes()
+	// every transaction is a quoted string followed by a comma
+	tx := sizes[len(sizes)-1]/3 + 3
+	return int64(MaxBundleLength*tx + requestOverhead)
+}
+
+// NodeService implements the commands of the node API served by a Server.
+// The Duration of responses is filled in by the Server when left zero.
+// Embed UnimplementedNodeService to serve a subset of the commands.
+type NodeService interface {
+	GetNodeInfo(req *GetNodeInfoRequest) (*GetNodeInfoResponse, error)
+	GetNeighbors(req *GetNeighborsRequest) (*GetNeighborsResponse, error)
+	AddNeighbors(req *AddNeighborsRequest) (*AddNeighborsResponse, error)
+	RemoveNeighbors(req *RemoveNeighborsRequest) (*RemoveNeighborsResponse, error)
+	GetTips(req *GetTipsRequest) (*GetTipsResponse, error)
+	FindTransactions(req *FindTransactionsRequest) (*FindTransactionsResponse, error)
+	GetTrytes(req *GetTrytesRequest) (*GetTrytesResponse, error)
+	GetInclusionStates(req *GetInclusionStatesRequest) (*GetInclusionStatesResponse, error)
+	GetBalances(req *GetBalancesRequest) (*GetBalancesResponse, error)
+	GetTransactionsToApprove(req *GetTransactionsToApproveRequest) (*GetTransactionsToApproveResponse, error)
+	AttachToTangle(req *AttachToTangleRequest) (*AttachToTangleResponse, error)
+	InterruptAttachingToTangle(req *InterruptAttachingToTangleRequest) error
+	BroadcastTransactions(req *BroadcastTransactionsRequest) error
+	StoreTransactions(req *StoreTransactionsRequest) error
+	CheckConsistency(req *CheckConsistencyRequest) (*CheckConsistencyResponse, error)
+}
+
+func notAvailable(command string) error {
+	return fmt.Errorf("command [%s] is not available on this node", command)
+}
+
+// UnimplementedNodeService answers every command with an error.
+type UnimplementedNodeService struct{}
+
+// GetNodeInfo is not available.
+func (UnimplementedNodeService) GetNodeInfo(*GetNodeInfoRequest) (*GetNodeInfoResponse, error) {
+	return nil, notAvailable("getNodeInfo")
+}
+
+// GetNeighbors is not available.
+func (UnimplementedNodeService) GetNeighbors(*GetNeighborsRequest) (*GetNeighborsResponse, error) {
+	return nil, notAvailable("getNeighbors")
+}
+
+// AddNeighbors is not available.
+func (UnimplementedNodeService) AddNeighbors(*AddNeighborsRequest) (*AddNeighborsResponse, error) {
+	return nil, notAvailable("addNeighbors")
+}
+
+// RemoveNeighbors is not available.
+func (UnimplementedNodeService) RemoveNeighbors(*RemoveNeighborsRequest) (*RemoveNeighborsResponse, error) {
+	return nil, notAvailable("removeNeighbors")
+}
+
+// GetTips is not available.
+func (UnimplementedNodeService) GetTips(*GetTipsRequest) (*GetTipsResponse, error) {
+	return nil, notAvailable("getTips")
+}
+
+// FindTransactions is not available.
+func (UnimplementedNodeService) FindTransactions(*FindTransactionsRequest) (*FindTransactionsResponse, error) {
+	return nil, notAvailable("findTransactions")
+}
+
+// GetTrytes is not available.
+func (UnimplementedNodeService) GetTrytes(*GetTrytesRequest) (*GetTrytesResponse, error) {
+	return nil, notAvailable("getTrytes")
+}
+
+// GetInclusionStates is not available.
+func (UnimplementedNodeService) GetInclusionStates(*GetInclusionStatesRequest) (*GetInclusionStatesResponse, error) {
+	return nil, notAvailable("getInclusionStates")
+}
+
+// GetBalances is not available.
+func (UnimplementedNodeService) GetBalances(*GetBalancesRequest) (*GetBalancesResponse, error) {
+	return nil, notAvailable("getBalances")
+}
+
+// GetTransactionsToApprove is not available.
+func (UnimplementedNodeService) GetTransactionsToApprove(*GetTransactionsToApproveRequest) (*GetTransactionsToApproveResponse, error) {
+	return nil, notAvailable("getTransactionsToApprove")
+}
+
+// AttachToTangle is not available.
+func (UnimplementedNodeService) AttachToTangle(*AttachToTangleRequest) (*AttachToTangleResponse, error) {
+	return nil, notAvailable("attachToTangle")
+}
+
+// InterruptAttachingToTangle is not available.
+func (UnimplementedNodeService) InterruptAttachingToTangle(*InterruptAttachingToTangleRequest) error {
+	return notAvailable("interruptAttachingToTangle")
+}
+
+// BroadcastTransactions is not available.
+func (UnimplementedNodeService) BroadcastTransactions(*BroadcastTransactionsRequest) error {
+	return notAvailable("broadcastTransactions")
+}
+
+// StoreTransactions is not available.
+func (UnimplementedNodeService) StoreTransactions(*StoreTransactionsRequest) error {
+	return notAvailable("storeTransactions")
+}
+
+// CheckConsistency is not available.
+func (UnimplementedNodeService) CheckConsistency(*CheckConsistencyRequest) (*CheckConsistencyResponse, error) {
+	return nil, notAvailable("checkConsistency")
+}
+
+// durationResponse is the response of commands which return nothing else.
+type durationResponse struct {
+	Duration int64 `json:"duration"`
+}
+
+// Server is an http.Handler serving the node API from a NodeService.
+// Errors are answered with an ErrorResponse and status 400, or the status
+// of a *NodeError when the service proxies another node.
+type Server struct {
+	svc NodeService
+}
+
+// NewServer returns a Server dispatching commands to svc.
+func NewServer(svc NodeService) *Server {
+	return &Server{svc: svc}
+}
+
+func writeResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-IOTA-API-Version", APIVersion)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) // nolint: errcheck
+}
+
+func writeErrorResponse(w http.ResponseWriter, err error) {
+	status, msg := http.StatusBadRequest, err.Error()
+	if ne, ok := err.(*NodeError); ok {
+		// the message of the proxied node, without the command
+		msg = ne.Message
+		if ne.StatusCode >= 400 {
+			status = ne.StatusCode
+		}
+	}
+	writeResponse(w, status, &ErrorResponse{Error: msg})
+}
+
+// ServeHTTP handles a command. Commands are POSTed, and bodies larger than
+// the largest attachToTangle request are rejected.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeResponse(w, http.StatusMethodNotAllowed, &ErrorResponse{Error: "Invalid request method: " + r.Method})
+		return
+	}
+	if r.Header.Get("X-IOTA-API-Version") == "" {
+		writeErrorResponse(w, ErrInvalidAPIVersion)
+		return
+	}
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize()))
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	var cmd struct {
+		Command string `json:"command"`
+	}
+	if err = json.Unmarshal(b, &cmd); err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+	if cmd.Command == "" {
+		writeErrorResponse(w, errors.New("COMMAND parameter has not been specified in the request."))
+		return
+	}
+
+	resp, err := s.dispatch(cmd.Command, b)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+	setDuration(resp, int64(time.Since(start)/time.Millisecond))
+	writeResponse(w, http.StatusOK, resp)
+}
+
+// setDuration sets the Duration field of the response resp points to if it is zero.
+func setDuration(resp interface{}, d int64) {
+	v := reflect.ValueOf(resp)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	f := v.Elem().FieldByName("Duration")
+	if f.IsValid() && f.Kind() == reflect.Int64 && f.CanSet() && f.Int() == 0 {
+		f.SetInt(d)
+	}
+}
+
+// nolint: gocyclo
+func (s *Server) dispatch(command string, b []byte) (interface{}, error) {
+	var (
+		resp interface{}
+		err  error
+	)
+
+	switch command {
+	case "getNodeInfo":
+		req := &GetNodeInfoRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetNodeInfoResponse
+			r, err = s.svc.GetNodeInfo(req)
+			resp = r
+		}
+
+	case "getNeighbors":
+		req := &GetNeighborsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetNeighborsResponse
+			r, err = s.svc.GetNeighbors(req)
+			resp = r
+		}
+
+	case "addNeighbors":
+		req := &AddNeighborsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *AddNeighborsResponse
+			r, err = s.svc.AddNeighbors(req)
+			resp = r
+		}
+
+	case "removeNeighbors":
+		req := &RemoveNeighborsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *RemoveNeighborsResponse
+			r, err = s.svc.RemoveNeighbors(req)
+			resp = r
+		}
+
+	case "getTips":
+		req := &GetTipsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetTipsResponse
+			r, err = s.svc.GetTips(req)
+			resp = r
+		}
+
+	case "findTransactions":
+		req := &FindTransactionsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *FindTransactionsResponse
+			r, err = s.svc.FindTransactions(req)
+			resp = r
+		}
+
+	case "getTrytes":
+		req := &GetTrytesRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetTrytesResponse
+			r, err = s.svc.GetTrytes(req)
+			resp = r
+		}
+
+	case "getInclusionStates":
+		req := &GetInclusionStatesRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetInclusionStatesResponse
+			r, err = s.svc.GetInclusionStates(req)
+			resp = r
+		}
+
+	case "getBalances":
+		req := &GetBalancesRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetBalancesResponse
+			r, err = s.svc.GetBalances(req)
+			resp = r
+		}
+
+	case "getTransactionsToApprove":
+		req := &GetTransactionsToApproveRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *GetTransactionsToApproveResponse
+			r, err = s.svc.GetTransactionsToApprove(req)
+			resp = r
+		}
+
+	case "attachToTangle":
+		req := &AttachToTangleRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *AttachToTangleResponse
+			r, err = s.svc.AttachToTangle(req)
+			resp = r
+		}
+
+	case "interruptAttachingToTangle":
+		req := &InterruptAttachingToTangleRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			err = s.svc.InterruptAttachingToTangle(req)
+			resp = &durationResponse{}
+		}
+
+	case "broadcastTransactions":
+		req := &BroadcastTransactionsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			err = s.svc.BroadcastTransactions(req)
+			resp = &durationResponse{}
+		}
+
+	case "storeTransactions":
+		req := &StoreTransactionsRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			err = s.svc.StoreTransactions(req)
+			resp = &durationResponse{}
+		}
+
+	case "checkConsistency":
+		req := &CheckConsistencyRequest{}
+		if err = json.Unmarshal(b, req); err == nil {
+			var r *CheckConsistencyResponse
+			r, err = s.svc.CheckConsistency(req)
+			resp = r
+		}
+
+	default:
+		err = fmt.Errorf("command [%s] is unknown", command)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	// resp holds a typed nil if the service returned neither a response nor an error
+	if v := reflect.ValueOf(resp); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, fmt.Errorf("command [%s] returned no response", command)
+	}
+	return resp, nil
+}
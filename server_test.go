@@ -0,0 +1,137 @@
+package giota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type tipsService struct {
+	UnimplementedNodeService
+	tips []Trytes
+}
+
+func (s *tipsService) GetTips(*GetTipsRequest) (*GetTipsResponse, error) {
+	return &GetTipsResponse{Hashes: s.tips}, nil
+}
+
+func (s *tipsService) GetBalances(req *GetBalancesRequest) (*GetBalancesResponse, error) {
+	return nil, &NodeError{Command: "getBalances", StatusCode: http.StatusServiceUnavailable, Message: "not synced"}
+}
+
+func (s *tipsService) StoreTransactions(req *StoreTransactionsRequest) error {
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	svc := &tipsService{tips: []Trytes{EmptyHash}}
+	srv := httptest.NewServer(NewServer(svc))
+	defer srv.Close()
+
+	api := NewAPI(srv.URL, srv.Client())
+	api.SetRetryPolicy(NoRetry)
+
+	tips, err := api.GetTips()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tips.Hashes) != 1 || tips.Hashes[0] != EmptyHash {
+		t.Errorf("GetTips() = %v", tips.Hashes)
+	}
+
+	if err = api.StoreTransactions(nil); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name   string
+		call   func() error
+		status int
+		msg    string
+	}{
+		{
+			name:   "unimplemented",
+			call:   func() error { _, err := api.GetNodeInfo(); return err },
+			status: http.StatusBadRequest,
+			msg:    "command [getNodeInfo] is not available on this node",
+		},
+		{
+			name:   "node error",
+			call:   func() error { _, err := api.getBalances([]Address{}, 100); return err },
+			status: http.StatusServiceUnavailable,
+			msg:    "not synced",
+		},
+		{
+			name:   "unknown",
+			call:   func() error { return api.do(map[string]string{"command": "foo"}, nil) },
+			status: http.StatusBadRequest,
+			msg:    "command [foo] is unknown",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		ne, ok := err.(*NodeError)
+		if !ok {
+			t.Errorf("%s: expected a NodeError but got %v", tt.name, err)
+			continue
+		}
+		if ne.StatusCode != tt.status || ne.Message != tt.msg {
+			t.Errorf("%s: got status %d and %q", tt.name, ne.StatusCode, ne.Message)
+		}
+	}
+}
+
+func TestServerAPIVersion(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&tipsService{}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"command":"getTips"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var e ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || e.Error != ErrInvalidAPIVersion.Error() {
+		t.Errorf("request without version got %d %q", resp.StatusCode, e.Error)
+	}
+}
+
+func TestServerRequestLimits(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&tipsService{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET got %d", resp.StatusCode)
+	}
+
+	body := `{"command":"getTips","padding":"` + strings.Repeat("9", int(maxRequestSize())) + `"}`
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-IOTA-API-Version", APIVersion)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var e ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || e.Error == "" {
+		t.Errorf("request larger than %d bytes got %d %q", maxRequestSize(), resp.StatusCode, e.Error)
+	}
+}
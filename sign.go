@@ -217,13 +217,9 @@ func (a Address) Checksum() Trytes {
 	return a.Hash()[81-9 : 81]
 }
 
-// Hash hashes the address and returns trytes
+// Hash hashes the address in the current hash scheme and returns trytes
 func (a Address) Hash() Trytes {
-	k := NewKerl()
-	t := Trytes(a).Trits()
-	k.Absorb(t)
-	h, _ := k.Squeeze(HashSize)
-	return h.Trytes()
+	return hashTrits(CurrentHashScheme().Address, Trytes(a).Trits())
 }
 
 // DecodePubKey returns the public key stored in the address
@@ -0,0 +1,513 @@
+// Package tangle simulates the ledger of a node. Transactions are stored as a DAG
+// keyed by their hash, tips are selected by a weighted random walk, and a
+// coordinator issues milestones which confirm the transactions they reference.
+package tangle
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+// Default values of Config.
+const (
+	DefaultAlpha           = 0.001
+	DefaultMaxBundleLength = giota.MaxBundleLength
+)
+
+// Config is the configuration of a Tangle.
+type Config struct {
+	// Depth is the number of milestones below the latest one where random
+	// walks start. It defaults to giota.Depth.
+	Depth int64
+
+	// Alpha is the bias of the random walk towards heavier transactions.
+	// It defaults to DefaultAlpha.
+	Alpha float64
+
+	// UniformWalk makes the walk uniformly random, ignoring Alpha.
+	UniformWalk bool
+
+	// MaxBundleLength is the number of transactions above which bundles are
+	// rejected. It defaults to DefaultMaxBundleLength.
+	MaxBundleLength int64
+
+	// ConfirmAll makes every milestone confirm all the transactions stored,
+	// not only those it references, so tests confirm deterministically.
+	ConfirmAll bool
+
+	// MilestoneInterval is the interval at which the coordinator issues
+	// milestones after Start. Zero disables the coordinator.
+	MilestoneInterval time.Duration
+
+	// Coordinator is the address of milestone transactions.
+	// It defaults to giota.EmptyAddress.
+	Coordinator giota.Address
+
+	// Validate checks a bundle before it is accepted.
+	// It defaults to Bundle.IsValid.
+	Validate func(giota.Bundle) error
+
+	// OnTransaction is called for every transaction added to the DAG and
+	// OnConfirmation for every transaction confirmed by the milestone at index.
+	// They are called without holding the lock of the tangle.
+	OnTransaction  func(tx *giota.Transaction)
+	OnConfirmation func(index int64, tx *giota.Transaction)
+}
+
+type vertex struct {
+	tx        giota.Transaction
+	hash      giota.Trytes
+	trunk     *vertex
+	branch    *vertex
+	approvers []*vertex
+
+	// milestone is the index of the milestone which confirmed the transaction.
+	milestone int64
+
+	// weight is the cumulative weight of the transaction: the number of
+	// transactions approving it directly or indirectly, plus one for itself.
+	weight int
+}
+
+// Tangle is a DAG of transactions.
+type Tangle struct {
+	cfg Config
+
+	mu         sync.RWMutex
+	vertices   map[giota.Trytes]*vertex
+	order      []*vertex
+	pending    map[giota.Trytes][]giota.Transaction
+	milestones []*vertex
+	rand       *rand.Rand
+	stop       chan struct{}
+}
+
+// New returns an empty Tangle.
+func New(cfg Config) *Tangle {
+	if cfg.Depth <= 0 {
+		cfg.Depth = giota.Depth
+	}
+	switch {
+	case cfg.UniformWalk:
+		cfg.Alpha = 0
+	case cfg.Alpha == 0:
+		cfg.Alpha = DefaultAlpha
+	}
+	if cfg.MaxBundleLength <= 0 {
+		cfg.MaxBundleLength = DefaultMaxBundleLength
+	}
+	if cfg.Coordinator == "" {
+		cfg.Coordinator = giota.EmptyAddress
+	}
+	if cfg.Validate == nil {
+		cfg.Validate = giota.Bundle.IsValid
+	}
+
+	return &Tangle{
+		cfg:      cfg,
+		vertices: make(map[giota.Trytes]*vertex),
+		pending:  make(map[giota.Trytes][]giota.Transaction),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start starts the coordinator if MilestoneInterval is set.
+func (t *Tangle) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cfg.MilestoneInterval <= 0 || t.stop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	t.stop = stop
+
+	go func() {
+		tick := time.NewTicker(t.cfg.MilestoneInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				t.IssueMilestone()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the coordinator.
+func (t *Tangle) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+// Store adds transactions to the tangle. Transactions are kept aside until
+// their bundle is complete, then the bundle is validated and attached to the
+// DAG. An error is returned for invalid bundles, which are dropped, and for
+// transactions of bundles longer than MaxBundleLength.
+func (t *Tangle) Store(txs []giota.Transaction) error {
+	added, err := t.store(txs)
+	if t.cfg.OnTransaction != nil {
+		for _, v := range added {
+			t.cfg.OnTransaction(&v.tx)
+		}
+	}
+	return err
+}
+
+func (t *Tangle) store(txs []giota.Transaction) ([]*vertex, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var added []*vertex
+	for _, tx := range txs {
+		if _, exist := t.vertices[tx.Hash()]; exist {
+			continue
+		}
+		if tx.LastIndex < 0 || tx.LastIndex >= t.cfg.MaxBundleLength {
+			return added, fmt.Errorf("invalid last index %d of bundle %s", tx.LastIndex, tx.Bundle)
+		}
+
+		bs := append(t.pending[tx.Bundle], tx)
+		t.pending[tx.Bundle] = bs
+
+		bundle, ok := assemble(bs)
+		if !ok {
+			continue
+		}
+		delete(t.pending, tx.Bundle)
+
+		if err := t.cfg.Validate(bundle); err != nil {
+			return added, fmt.Errorf("invalid bundle %s: %s", tx.Bundle, err)
+		}
+
+		for i := range bundle {
+			added = append(added, t.add(&bundle[i]))
+		}
+	}
+	return added, nil
+}
+
+// assemble returns the bundle made of txs in CurrentIndex order if all of its
+// transactions are present.
+func assemble(txs []giota.Transaction) (giota.Bundle, bool) {
+	last := txs[0].LastIndex
+	bundle := make(giota.Bundle, last+1)
+	seen := make([]bool, last+1)
+
+	for _, tx := range txs {
+		if tx.CurrentIndex < 0 || tx.CurrentIndex > last || tx.LastIndex != last {
+			continue
+		}
+		bundle[tx.CurrentIndex] = tx
+		seen[tx.CurrentIndex] = true
+	}
+
+	for _, s := range seen {
+		if !s {
+			return nil, false
+		}
+	}
+	return bundle, true
+}
+
+// add links tx into the DAG. The caller must hold t.mu.
+func (t *Tangle) add(tx *giota.Transaction) *vertex {
+	h := tx.Hash()
+	if v, exist := t.vertices[h]; exist {
+		return v
+	}
+
+	v := &vertex{
+		tx:     *tx,
+		hash:   h,
+		trunk:  t.vertices[tx.TrunkTransaction],
+		branch: t.vertices[tx.BranchTransaction],
+	}
+
+	if v.trunk != nil {
+		v.trunk.approvers = append(v.trunk.approvers, v)
+	}
+	if v.branch != nil && v.branch != v.trunk {
+		v.branch.approvers = append(v.branch.approvers, v)
+	}
+
+	// link transactions which arrived before their parents
+	for _, o := range t.order {
+		if o.trunk == nil && o.tx.TrunkTransaction == h {
+			o.trunk = v
+			v.approvers = append(v.approvers, o)
+		}
+		if o.branch == nil && o.tx.BranchTransaction == h {
+			o.branch = v
+			if o.trunk != v {
+				v.approvers = append(v.approvers, o)
+			}
+		}
+	}
+
+	t.vertices[h] = v
+	t.order = append(t.order, v)
+	addWeight(v)
+	return v
+}
+
+// Transaction returns the transaction with hash h.
+func (t *Tangle) Transaction(h giota.Trytes) (giota.Transaction, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	v, exist := t.vertices[h]
+	if !exist {
+		return giota.Transaction{}, false
+	}
+	return v.tx, true
+}
+
+func padTag(tag giota.Trytes) giota.Trytes {
+	if len(tag) >= giota.TagTrinarySize/3 {
+		return tag[:giota.TagTrinarySize/3]
+	}
+	return tag + giota.Trytes(strings.Repeat("9", giota.TagTrinarySize/3-len(tag)))
+}
+
+// Find returns the hashes of the transactions matching all non-empty fields of req.
+func (t *Tangle) Find(req *giota.FindTransactionsRequest) []giota.Trytes {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	adrs := make(map[giota.Address]bool)
+	for _, a := range req.Addresses {
+		adrs[a] = true
+	}
+	bundles := make(map[giota.Trytes]bool)
+	for _, b := range req.Bundles {
+		bundles[b] = true
+	}
+	tags := make(map[giota.Trytes]bool)
+	for _, tag := range req.Tags {
+		tags[padTag(tag)] = true
+	}
+	approvees := make(map[giota.Trytes]bool)
+	for _, a := range req.Approvees {
+		approvees[a] = true
+	}
+
+	hashes := []giota.Trytes{}
+	if len(adrs)+len(bundles)+len(tags)+len(approvees) == 0 {
+		return hashes
+	}
+
+	for _, v := range t.order {
+		switch {
+		case len(adrs) > 0 && !adrs[v.tx.Address]:
+		case len(bundles) > 0 && !bundles[v.tx.Bundle]:
+		case len(tags) > 0 && !tags[padTag(v.tx.Tag)]:
+		case len(approvees) > 0 && !approvees[v.tx.TrunkTransaction] && !approvees[v.tx.BranchTransaction]:
+		default:
+			hashes = append(hashes, v.hash)
+		}
+	}
+	return hashes
+}
+
+// isOutput returns true if tx carries a range proof, i.e. it receives value.
+func isOutput(tx *giota.Transaction) bool {
+	return len(tx.RangeProof) >= 6 && tx.RangeProof[0:6] != "999999"
+}
+
+// Balances returns the confidential balances of adrs as returned by the
+// getBalances command. The balance of an address is the list of encrypted
+// values of the confirmed outputs to it, or "0" if it has none or was spent from.
+func (t *Tangle) Balances(adrs []giota.Address) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	index := make(map[giota.Address]int)
+	for i, a := range adrs {
+		index[a] = i
+	}
+
+	values := make([][]string, len(adrs))
+	spent := make([]bool, len(adrs))
+	for _, v := range t.order {
+		i, ok := index[v.tx.Address]
+		if !ok || v.milestone == 0 {
+			continue
+		}
+
+		if !isOutput(&v.tx) {
+			spent[i] = true
+			continue
+		}
+
+		if val := strings.TrimRight(string(v.tx.Value), "9"); val != "" {
+			values[i] = append(values[i], val)
+		}
+	}
+
+	bals := make([]string, len(adrs))
+	for i := range adrs {
+		if spent[i] || len(values[i]) == 0 {
+			bals[i] = "0"
+			continue
+		}
+		bals[i] = strings.Join(values[i], ",")
+	}
+	return bals
+}
+
+// InclusionStates returns whether each of hashes is confirmed by a milestone.
+func (t *Tangle) InclusionStates(hashes []giota.Trytes) []bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	states := make([]bool, len(hashes))
+	for i, h := range hashes {
+		if v, exist := t.vertices[h]; exist {
+			states[i] = v.milestone > 0
+		}
+	}
+	return states
+}
+
+// tips returns the transactions which are not approved yet. The caller must hold t.mu.
+func (t *Tangle) tips() []*vertex {
+	var tips []*vertex
+	for _, v := range t.order {
+		if len(v.approvers) == 0 {
+			tips = append(tips, v)
+		}
+	}
+	return tips
+}
+
+// Tips returns the hashes of the transactions which are not approved yet.
+func (t *Tangle) Tips() []giota.Trytes {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var hashes []giota.Trytes
+	for _, v := range t.tips() {
+		hashes = append(hashes, v.hash)
+	}
+	return hashes
+}
+
+// CheckConsistency checks that all tails are known tail transactions.
+func (t *Tangle) CheckConsistency(tails []giota.Trytes) (bool, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, h := range tails {
+		v, exist := t.vertices[h]
+		switch {
+		case !exist:
+			return false, fmt.Sprintf("transaction %s is unknown", h)
+		case v.tx.CurrentIndex != 0:
+			return false, fmt.Sprintf("transaction %s is not a tail", h)
+		}
+	}
+	return true, ""
+}
+
+// Milestone returns the hash and index of the latest milestone. The hash is
+// giota.EmptyHash and the index 0 before the first milestone.
+func (t *Tangle) Milestone() (giota.Trytes, int64) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.milestones) == 0 {
+		return giota.EmptyHash, 0
+	}
+	return t.milestones[len(t.milestones)-1].hash, int64(len(t.milestones))
+}
+
+// IssueMilestone attaches a coordinator milestone approving two tips selected
+// by random walks and confirms all transactions it references, or all those
+// stored if ConfirmAll is set.
+func (t *Tangle) IssueMilestone() (giota.Trytes, int64) {
+	m, confirmed := t.issueMilestone()
+	if t.cfg.OnTransaction != nil {
+		t.cfg.OnTransaction(&m.tx)
+	}
+	if t.cfg.OnConfirmation != nil {
+		for _, v := range confirmed {
+			t.cfg.OnConfirmation(m.milestone, &v.tx)
+		}
+	}
+	return m.hash, m.milestone
+}
+
+func (t *Tangle) issueMilestone() (*vertex, []*vertex) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	index := int64(len(t.milestones)) + 1
+	trunk := t.walk(t.cfg.Depth)
+	branch := t.walk(t.cfg.Depth)
+
+	tx := giota.Transaction{
+		Address:           t.cfg.Coordinator,
+		ObsoleteTag:       giota.Int2Trits(index, giota.ObsoleteTagTrinarySize).Trytes(),
+		Timestamp:         time.Now(),
+		Bundle:            giota.EmptyHash,
+		TrunkTransaction:  trunk,
+		BranchTransaction: branch,
+	}
+
+	m := t.add(&tx)
+	t.milestones = append(t.milestones, m)
+
+	// confirm everything referenced by the milestone
+	var confirmed []*vertex
+	stack := []*vertex{m}
+	if t.cfg.ConfirmAll {
+		stack = append(stack, t.order...)
+	}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if v == nil || v.milestone > 0 {
+			continue
+		}
+		v.milestone = index
+		confirmed = append(confirmed, v)
+		stack = append(stack, v.trunk, v.branch)
+	}
+	return m, confirmed
+}
+
+// CumulativeWeight returns the number of transactions approving the transaction
+// with hash h directly or indirectly, plus one for itself.
+func (t *Tangle) CumulativeWeight(h giota.Trytes) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	v, exist := t.vertices[h]
+	if !exist {
+		return 0
+	}
+	return v.weight
+}
+
+// Len returns the number of transactions in the tangle.
+func (t *Tangle) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.order)
+}
@@ -0,0 +1,310 @@
+package tangle
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peterdouglas/giota"
+)
+
+var (
+	testAddress = giota.Address(strings.Repeat("A", 81))
+	errInvalid  = errors.New("invalid")
+)
+
+func noValidation(giota.Bundle) error { return nil }
+
+// testBundle returns a bundle of n transactions approving trunk and branch.
+func testBundle(id int64, n int64, trunk, branch giota.Trytes) []giota.Transaction {
+	bundle := giota.Int2Trits(id, giota.BundleTrinarySize).Trytes()
+	txs := make([]giota.Transaction, n)
+	for i := n - 1; i >= 0; i-- {
+		txs[i] = giota.Transaction{
+			Address:           testAddress,
+			Timestamp:         time.Unix(1500000000, 0),
+			CurrentIndex:      i,
+			LastIndex:         n - 1,
+			Bundle:            bundle,
+			TrunkTransaction:  trunk,
+			BranchTransaction: branch,
+		}
+		if i < n-1 {
+			txs[i].TrunkTransaction = txs[i+1].Hash()
+			txs[i].BranchTransaction = trunk
+		}
+	}
+	return txs
+}
+
+func TestStoreValidatesCompleteBundles(t *testing.T) {
+	var validated int
+	tg := New(Config{Validate: func(b giota.Bundle) error {
+		validated++
+		if b[0].Bundle == giota.Int2Trits(2, giota.BundleTrinarySize).Trytes() {
+			return errInvalid
+		}
+		return nil
+	}})
+
+	b1 := testBundle(1, 3, giota.EmptyHash, giota.EmptyHash)
+	if err := tg.Store(b1[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if validated != 0 || tg.Len() != 0 {
+		t.Fatal("incomplete bundle was accepted")
+	}
+	if err := tg.Store(b1[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if validated != 1 || tg.Len() != 3 {
+		t.Fatalf("complete bundle was not accepted, got %d transactions", tg.Len())
+	}
+
+	b2 := testBundle(2, 1, b1[0].Hash(), b1[0].Hash())
+	if err := tg.Store(b2); err == nil {
+		t.Error("invalid bundle was accepted")
+	}
+	if tg.Len() != 3 {
+		t.Errorf("invalid bundle was stored")
+	}
+
+	if _, ok := tg.Transaction(b1[2].Hash()); !ok {
+		t.Error("Transaction() did not find a stored transaction")
+	}
+}
+
+func TestCumulativeWeight(t *testing.T) {
+	tg := New(Config{Validate: noValidation})
+
+	// a <- b, a <- c, b <- d, c <- d
+	a := testBundle(1, 1, giota.EmptyHash, giota.EmptyHash)
+	b := testBundle(2, 1, a[0].Hash(), a[0].Hash())
+	c := testBundle(3, 1, a[0].Hash(), a[0].Hash())
+	d := testBundle(4, 1, b[0].Hash(), c[0].Hash())
+
+	// d arrives first and must be linked once its parents arrive
+	for _, txs := range [][]giota.Transaction{d, b, c, a} {
+		if err := tg.Store(txs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		tx giota.Transaction
+		w  int
+	}{
+		{a[0], 4},
+		{b[0], 2},
+		{c[0], 2},
+		{d[0], 1},
+	}
+	for i, tt := range tests {
+		if w := tg.CumulativeWeight(tt.tx.Hash()); w != tt.w {
+			t.Errorf("%d: CumulativeWeight() = %d, expected %d", i, w, tt.w)
+		}
+	}
+
+	tips := tg.Tips()
+	if len(tips) != 1 || tips[0] != d[0].Hash() {
+		t.Errorf("Tips() = %v, expected only d", tips)
+	}
+}
+
+func TestCumulativeWeightRandomOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// every transaction approves two earlier ones
+	txs := make([][]giota.Transaction, 60)
+	parents := make([][2]int, len(txs))
+	for i := range txs {
+		trunk, branch := giota.EmptyHash, giota.EmptyHash
+		if i > 0 {
+			parents[i] = [2]int{r.Intn(i), r.Intn(i)}
+			trunk, branch = txs[parents[i][0]][0].Hash(), txs[parents[i][1]][0].Hash()
+		} else {
+			parents[i] = [2]int{-1, -1}
+		}
+		txs[i] = testBundle(int64(i+1), 1, trunk, branch)
+	}
+
+	tg := New(Config{Validate: noValidation})
+	for _, i := range r.Perm(len(txs)) {
+		if err := tg.Store(txs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range txs {
+		// count the transactions which approve i directly or indirectly
+		w := 0
+		for j := range txs {
+			stack, seen := []int{j}, make(map[int]bool)
+			for len(stack) > 0 && !seen[i] {
+				k := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if k < 0 || seen[k] {
+					continue
+				}
+				seen[k] = true
+				stack = append(stack, parents[k][0], parents[k][1])
+			}
+			if seen[i] {
+				w++
+			}
+		}
+		if cw := tg.CumulativeWeight(txs[i][0].Hash()); cw != w {
+			t.Errorf("CumulativeWeight() of %d = %d, expected %d", i, cw, w)
+		}
+	}
+}
+
+func TestWalkPrefersHeavySubtangle(t *testing.T) {
+	tg := New(Config{Validate: noValidation, Alpha: 10})
+
+	root := testBundle(1, 1, giota.EmptyHash, giota.EmptyHash)
+	lazy := testBundle(2, 1, root[0].Hash(), root[0].Hash())
+	heavy := testBundle(3, 5, root[0].Hash(), root[0].Hash())
+	for _, txs := range [][]giota.Transaction{root, lazy, heavy} {
+		if err := tg.Store(txs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		trunk, branch, err := tg.TransactionsToApprove(0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trunk != heavy[0].Hash() || branch != heavy[0].Hash() {
+			t.Fatal("random walk selected the lazy tip")
+		}
+	}
+
+	if _, _, err := tg.TransactionsToApprove(0, giota.EmptyHash); err == nil {
+		t.Error("TransactionsToApprove() accepted an unknown reference")
+	}
+}
+
+func TestMilestones(t *testing.T) {
+	tg := New(Config{Validate: noValidation})
+
+	if h, idx := tg.Milestone(); h != giota.EmptyHash || idx != 0 {
+		t.Errorf("Milestone() = %s, %d before the first milestone", h, idx)
+	}
+
+	b1 := testBundle(1, 2, giota.EmptyHash, giota.EmptyHash)
+	if err := tg.Store(b1); err != nil {
+		t.Fatal(err)
+	}
+	hashes := []giota.Trytes{b1[0].Hash(), b1[1].Hash()}
+
+	if states := tg.InclusionStates(hashes); states[0] || states[1] {
+		t.Error("transactions are confirmed before a milestone")
+	}
+
+	ms, idx := tg.IssueMilestone()
+	if idx != 1 {
+		t.Errorf("IssueMilestone() returned index %d, expected 1", idx)
+	}
+	if h, _ := tg.Milestone(); h != ms {
+		t.Error("Milestone() does not return the latest milestone")
+	}
+	if states := tg.InclusionStates(hashes); !states[0] || !states[1] {
+		t.Error("transactions are not confirmed after a milestone")
+	}
+
+	// the second milestone approves the first one, the only tip
+	ms2, _ := tg.IssueMilestone()
+
+	// walks one milestone deep start from the first milestone and ignore
+	// transactions which are not attached to it
+	b2 := testBundle(2, 1, giota.EmptyHash, giota.EmptyHash)
+	if err := tg.Store(b2); err != nil {
+		t.Fatal(err)
+	}
+	trunk, branch, err := tg.TransactionsToApprove(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trunk != ms2 || branch != ms2 {
+		t.Errorf("TransactionsToApprove() = %s, %s, expected the latest milestone", trunk, branch)
+	}
+}
+
+func TestCoordinator(t *testing.T) {
+	tg := New(Config{Validate: noValidation, MilestoneInterval: 10 * time.Millisecond})
+	tg.Start()
+	defer tg.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, idx := tg.Milestone(); idx >= 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("coordinator did not issue milestones")
+}
+
+func TestBalances(t *testing.T) {
+	tg := New(Config{Validate: noValidation})
+
+	out := testBundle(1, 1, giota.EmptyHash, giota.EmptyHash)
+	out[0].Value = "ABC"
+	out[0].RangeProof = "ABCDEF"
+	if err := tg.Store(out); err != nil {
+		t.Fatal(err)
+	}
+
+	other := giota.Address(strings.Repeat("B", 81))
+	if bals := tg.Balances([]giota.Address{testAddress, other}); bals[0] != "0" || bals[1] != "0" {
+		t.Errorf("Balances() = %v before confirmation", bals)
+	}
+
+	tg.IssueMilestone()
+	if bals := tg.Balances([]giota.Address{testAddress, other}); bals[0] != "ABC" || bals[1] != "0" {
+		t.Errorf("Balances() = %v after confirmation", bals)
+	}
+}
+
+func TestStoreRejectsBundleLength(t *testing.T) {
+	tg := New(Config{Validate: noValidation, MaxBundleLength: 3})
+
+	for _, last := range []int64{-1, 3, 1 << 40} {
+		tx := testBundle(1, 1, giota.EmptyHash, giota.EmptyHash)[0]
+		tx.LastIndex = last
+		if err := tg.Store([]giota.Transaction{tx}); err == nil {
+			t.Errorf("last index %d was accepted", last)
+		}
+	}
+	if err := tg.Store(testBundle(2, 3, giota.EmptyHash, giota.EmptyHash)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfirmAll(t *testing.T) {
+	tg := New(Config{Validate: noValidation, ConfirmAll: true, UniformWalk: true})
+	if tg.cfg.Alpha != 0 {
+		t.Errorf("uniform walk has alpha %f", tg.cfg.Alpha)
+	}
+
+	// bundles attached to unrelated parents aren't all referenced by a walk
+	var hashes []giota.Trytes
+	for i := int64(1); i <= 5; i++ {
+		b := testBundle(i, 1, giota.Int2Trits(i, giota.BundleTrinarySize).Trytes(), giota.EmptyHash)
+		if err := tg.Store(b); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, b[0].Hash())
+	}
+
+	tg.IssueMilestone()
+	for i, ok := range tg.InclusionStates(hashes) {
+		if !ok {
+			t.Errorf("transaction %d is not confirmed", i)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tangle
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/peterdouglas/giota"
+)
+
+// addWeight updates the cumulative weights after v was linked into the DAG.
+// v weighs itself and the transactions which arrived before it and approve
+// it, and every transaction v approves gains those it didn't count yet.
+// The caller must hold t.mu.
+func addWeight(v *vertex) {
+	future := approvers(v)
+	v.weight = len(future)
+
+	// the transactions approved by the earlier approvers of v before it arrived
+	var counted []map[*vertex]bool
+	for _, f := range future {
+		if f != v {
+			counted = append(counted, ancestors(f, v))
+		}
+	}
+
+	for a := range ancestors(v, nil) {
+		n := 1
+		for _, c := range counted {
+			if !c[a] {
+				n++
+			}
+		}
+		a.weight += n
+	}
+}
+
+// approvers returns v and the transactions approving it directly or
+// indirectly.
+func approvers(v *vertex) []*vertex {
+	seen := map[*vertex]bool{v: true}
+	all := []*vertex{v}
+	for i := 0; i < len(all); i++ {
+		for _, a := range all[i].approvers {
+			if !seen[a] {
+				seen[a] = true
+				all = append(all, a)
+			}
+		}
+	}
+	return all
+}
+
+// ancestors returns the transactions approved by v directly or indirectly,
+// without going through skip.
+func ancestors(v, skip *vertex) map[*vertex]bool {
+	seen := make(map[*vertex]bool)
+	stack := []*vertex{v}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, p := range []*vertex{u.trunk, u.branch} {
+			if p != nil && p != skip && !seen[p] {
+				seen[p] = true
+				stack = append(stack, p)
+			}
+		}
+	}
+	return seen
+}
+
+// entry returns the transaction where random walks start: the milestone depth
+// milestones below the latest one. If there are not enough milestones it returns
+// a virtual genesis approved by all transactions without known parents.
+// The caller must hold t.mu.
+func (t *Tangle) entry(depth int64) *vertex {
+	if idx := int64(len(t.milestones)) - depth; idx >= 1 {
+		return t.milestones[idx-1]
+	}
+
+	genesis := &vertex{hash: giota.EmptyHash}
+	for _, v := range t.order {
+		if v.trunk == nil && v.branch == nil {
+			genesis.approvers = append(genesis.approvers, v)
+		}
+	}
+	return genesis
+}
+
+// walk selects a tip with a random walk from the entry point at depth. At each
+// step the walk moves to an approver with a probability proportional to
+// exp(alpha * weight), so it favours heavier subtangles and avoids lazy tips.
+// The caller must hold t.mu.
+func (t *Tangle) walk(depth int64) giota.Trytes {
+	cur := t.entry(depth)
+
+	for len(cur.approvers) > 0 {
+		max := 0
+		for _, a := range cur.approvers {
+			if a.weight > max {
+				max = a.weight
+			}
+		}
+
+		var (
+			sum   float64
+			probs = make([]float64, len(cur.approvers))
+		)
+		for i, a := range cur.approvers {
+			probs[i] = math.Exp(t.cfg.Alpha * float64(a.weight-max))
+			sum += probs[i]
+		}
+
+		r := t.rand.Float64() * sum
+		next := cur.approvers[len(cur.approvers)-1]
+		for i, p := range probs {
+			if r < p {
+				next = cur.approvers[i]
+				break
+			}
+			r -= p
+		}
+		cur = next
+	}
+	return cur.hash
+}
+
+// TransactionsToApprove selects a trunk and a branch transaction with random
+// walks starting depth milestones below the latest one. Depth defaults to the
+// one of the Config if it is not positive. If reference is given it is used as
+// the trunk transaction.
+func (t *Tangle) TransactionsToApprove(depth int64, reference giota.Trytes) (giota.Trytes, giota.Trytes, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if depth <= 0 {
+		depth = t.cfg.Depth
+	}
+
+	trunk := t.walk(depth)
+	if reference != "" {
+		if _, exist := t.vertices[reference]; !exist {
+			return "", "", fmt.Errorf("reference transaction %s is unknown", reference)
+		}
+		trunk = reference
+	}
+	return trunk, t.walk(depth), nil
+}
@@ -0,0 +1,142 @@
+package giota
+
+import (
+	"errors"
+	"math/big"
+)
+
+// errors of Ternary
+var (
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrTritsOverflow  = errors.New("value doesn't fit in the trits")
+)
+
+var (
+	bigOne   = big.NewInt(1)
+	bigThree = big.NewInt(Radix)
+)
+
+// Ternary is an integer of any size, converted to and from balanced ternary
+// trits with the least significant trit first. Its methods return new values
+// and never modify their receiver or arguments.
+type Ternary struct {
+	v big.Int
+}
+
+// NewTernary returns the Ternary of v.
+func NewTernary(v int64) *Ternary {
+	t := &Ternary{}
+	t.v.SetInt64(v)
+	return t
+}
+
+// TernaryFromBig returns the Ternary of v.
+func TernaryFromBig(v *big.Int) *Ternary {
+	t := &Ternary{}
+	t.v.Set(v)
+	return t
+}
+
+// TernaryFromTrits returns the value of the trits tr of any length.
+func TernaryFromTrits(tr Trits) *Ternary {
+	t := &Ternary{}
+	for i := len(tr) - 1; i >= 0; i-- {
+		t.v.Mul(&t.v, bigThree)
+		t.v.Add(&t.v, big.NewInt(int64(tr[i])))
+	}
+	return t
+}
+
+// Big returns the value of t.
+func (t *Ternary) Big() *big.Int {
+	return new(big.Int).Set(&t.v)
+}
+
+// Int64 returns the value of t and whether it fits in an int64.
+func (t *Ternary) Int64() (int64, bool) {
+	return t.v.Int64(), t.v.IsInt64()
+}
+
+// String returns the value of t in decimal.
+func (t *Ternary) String() string {
+	return t.v.String()
+}
+
+// Trits returns the shortest trits of t, with no trailing 0 trit.
+func (t *Ternary) Trits() Trits {
+	var tr Trits
+	v := new(big.Int).Set(&t.v)
+	r := new(big.Int)
+	for v.Sign() != 0 {
+		v.DivMod(v, bigThree, r)
+		trit := int8(r.Int64())
+		if trit == 2 {
+			trit = -1
+			v.Add(v, bigOne)
+		}
+		tr = append(tr, trit)
+	}
+	return tr
+}
+
+// PaddedTrits returns size trits of t.
+func (t *Ternary) PaddedTrits(size int) (Trits, error) {
+	tr := t.Trits()
+	if len(tr) > size {
+		return nil, ErrTritsOverflow
+	}
+
+	p := make(Trits, size)
+	copy(p, tr)
+	return p, nil
+}
+
+// Cmp returns -1, 0 or +1 if t is less than, equal to or greater than u.
+func (t *Ternary) Cmp(u *Ternary) int {
+	return t.v.Cmp(&u.v)
+}
+
+// Sign returns -1, 0 or +1 if t is negative, zero or positive.
+func (t *Ternary) Sign() int {
+	return t.v.Sign()
+}
+
+// Neg returns -t.
+func (t *Ternary) Neg() *Ternary {
+	r := &Ternary{}
+	r.v.Neg(&t.v)
+	return r
+}
+
+// Add returns t+u.
+func (t *Ternary) Add(u *Ternary) *Ternary {
+	r := &Ternary{}
+	r.v.Add(&t.v, &u.v)
+	return r
+}
+
+// Sub returns t-u.
+func (t *Ternary) Sub(u *Ternary) *Ternary {
+	r := &Ternary{}
+	r.v.Sub(&t.v, &u.v)
+	return r
+}
+
+// Mul returns t*u.
+func (t *Ternary) Mul(u *Ternary) *Ternary {
+	r := &Ternary{}
+	r.v.Mul(&t.v, &u.v)
+	return r
+}
+
+// DivMod returns the quotient and the remainder of the Euclidean division of
+// t by u, the remainder being in [0, |u|).
+func (t *Ternary) DivMod(u *Ternary) (*Ternary, *Ternary, error) {
+	if u.v.Sign() == 0 {
+		return nil, nil, ErrDivisionByZero
+	}
+
+	q, m := &Ternary{}, &Ternary{}
+	q.v.DivMod(&t.v, &u.v, &m.v)
+	return q, m, nil
+}
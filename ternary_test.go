@@ -0,0 +1,103 @@
+package giota
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTernaryTrits(t *testing.T) {
+	tests := []struct {
+		v     int64
+		trits Trits
+	}{
+		{0, nil},
+		{1, Trits{1}},
+		{-1, Trits{-1}},
+		{2, Trits{-1, 1}},
+		{5, Trits{-1, -1, 1}},
+		{-13, Trits{-1, -1, -1}},
+		{42, Trits{0, -1, -1, -1, 1}},
+	}
+	for _, tt := range tests {
+		tr := NewTernary(tt.v).Trits()
+		if !tr.Equal(tt.trits) {
+			t.Errorf("Trits() of %d = %v, want %v", tt.v, tr, tt.trits)
+		}
+		if v, ok := TernaryFromTrits(tt.trits).Int64(); !ok || v != tt.v {
+			t.Errorf("TernaryFromTrits(%v) = %d", tt.trits, v)
+		}
+		if Int2Trits(tt.v, 5).Int() != TernaryFromTrits(Int2Trits(tt.v, 5)).v.Int64() {
+			t.Errorf("Ternary of %d differs from Trits.Int()", tt.v)
+		}
+	}
+
+	if _, err := NewTernary(42).PaddedTrits(4); err != ErrTritsOverflow {
+		t.Errorf("PaddedTrits() of too few trits returned %v", err)
+	}
+	tr, err := NewTernary(-1).PaddedTrits(3)
+	if err != nil || !tr.Equal(Trits{-1, 0, 0}) {
+		t.Errorf("PaddedTrits() = %v, %v", tr, err)
+	}
+}
+
+func TestTernaryArithmetic(t *testing.T) {
+	// 3^100 doesn't fit in an int64
+	big3, _ := new(big.Int).SetString("515377520732011331036461129765621272702107522001", 10)
+	x := TernaryFromBig(big3)
+	if tr := x.Trits(); len(tr) != 101 || tr[100] != 1 || TernaryFromTrits(tr).Cmp(x) != 0 {
+		t.Errorf("trits of 3^100 are %v", tr)
+	}
+
+	y := NewTernary(-7)
+	if x.Add(y).Sub(y).Cmp(x) != 0 {
+		t.Error("x+y-y != x")
+	}
+	if NewTernary(6).Mul(y).String() != "-42" {
+		t.Error("6*-7 != -42")
+	}
+	if y.Neg().Sign() != 1 || y.Sign() != -1 || NewTernary(0).Sign() != 0 {
+		t.Error("Sign() is wrong")
+	}
+
+	q, r, err := y.DivMod(NewTernary(3))
+	if err != nil || q.String() != "-3" || r.String() != "2" {
+		t.Errorf("-7 divmod 3 = %v, %v, %v", q, r, err)
+	}
+	if _, _, err = y.DivMod(NewTernary(0)); err != ErrDivisionByZero {
+		t.Errorf("division by zero returned %v", err)
+	}
+
+	if y.String() != "-7" || x.Big().Cmp(big3) != 0 {
+		t.Error("operations modified their operands")
+	}
+}
+
+func TestBytesTritsRoundTrip(t *testing.T) {
+	for _, tr := range []Trits{
+		Trytes("ABCDEFGHIJKLMNOPQRSTUVWXYZ9ABCDEFGHIJKLMNOPQRSTUVWXYZ9ABCDEFGHIJKLMNOPQRSTUVWXYZ9").Trits(),
+		Trytes("NNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNNN").Trits(),
+		make(Trits, TritHashLength),
+	} {
+		tr[TritHashLength-1] = 0
+		b, err := tr.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := BytesToTrits(b)
+		if err != nil || !back.Equal(tr) {
+			t.Errorf("BytesToTrits(Bytes(%s)) = %v, %v", tr.Trytes(), back, err)
+		}
+	}
+
+	// the values of 384 bits beyond 242 trits are reduced
+	b := make([]byte, ByteLength)
+	b[0] = 0x7f
+	tr, err := BytesToTrits(b)
+	if err != nil || tr[TritHashLength-1] != 0 {
+		t.Fatalf("BytesToTrits() = %v, %v", tr, err)
+	}
+	v := new(big.Int).SetBytes(b)
+	if d := new(big.Int).Sub(v, TernaryFromTrits(tr).Big()); new(big.Int).Mod(d, threeToHashLength).Sign() != 0 {
+		t.Error("BytesToTrits() is not congruent to the bytes")
+	}
+}
@@ -50,6 +50,10 @@ type Transaction struct {
 	AttachmentTimestampLowerBound Trytes
 	AttachmentTimestampUpperBound Trytes
 	Nonce                         Trytes
+
+	// Version is the version of the layout of the transaction,
+	// LayoutVersionDefault if zero.
+	Version int
 }
 
 // errors for tx
@@ -107,7 +111,7 @@ const (
 
 )
 
-// NewTransaction makes tx from trits.
+// NewTransaction makes tx from trits, in the layout of their version.
 func NewTransaction(trytes Trytes) (*Transaction, error) {
 	t := Transaction{}
 	if err := checkTx(trytes); err != nil {
@@ -128,66 +132,46 @@ func checkTx(trytes Trytes) error {
 	switch {
 	case err != nil:
 		return errors.New("invalid transaction " + err.Error())
-	case len(trytes) != transactionTrinarySize/3:
+	case !isLayoutSize(len(trytes) * 3):
 		return errors.New("invalid trits counts in transaction")
-	//case trytes[10296:10302] != "999999":
-	//	println(trytes[10296:10302])
-	//	return errors.New("invalid value in transaction")
 	default:
 		return nil
 	}
 }
 
 func (t *Transaction) parser(trits Trits) error {
-	var err error
-	t.SignatureMessageFragment = trits[SignatureMessageFragmentTrinaryOffset:SignatureMessageFragmentTrinarySize].Trytes()
-	t.Address, err = trits[AddressTrinaryOffset : AddressTrinaryOffset+AddressTrinarySize].Trytes().ToAddress()
+	l, err := layoutOfTrits(trits)
 	if err != nil {
 		return err
 	}
-	t.VectorP = trits[ValueTrinaryOffset : ValueTrinaryOffset+ValueTrinarySize].Trytes()
-	t.Value = trits[BlindingTrinaryOffset : BlindingTrinaryOffset+BlindingTrinarySize].Trytes()
-	t.RangeProof = trits[RangeProofTrinaryOffset : RangeProofTrinaryOffset+RangeProofTrinarySize].Trytes()
-	t.ObsoleteTag = trits[ObsoleteTagTrinaryOffset : ObsoleteTagTrinaryOffset+ObsoleteTagTrinarySize].Trytes()
-	timestamp := trits[TimestampTrinaryOffset : TimestampTrinaryOffset+TimestampTrinarySize].Int()
-	t.Timestamp = time.Unix(timestamp, 0)
-	t.CurrentIndex = trits[CurrentIndexTrinaryOffset : CurrentIndexTrinaryOffset+CurrentIndexTrinarySize].Int()
-	t.LastIndex = trits[LastIndexTrinaryOffset : LastIndexTrinaryOffset+LastIndexTrinarySize].Int()
-	t.Bundle = trits[BundleTrinaryOffset : BundleTrinaryOffset+BundleTrinarySize].Trytes()
-	t.TrunkTransaction = trits[TrunkTransactionTrinaryOffset : TrunkTransactionTrinaryOffset+TrunkTransactionTrinarySize].Trytes()
-	t.BranchTransaction = trits[BranchTransactionTrinaryOffset : BranchTransactionTrinaryOffset+BranchTransactionTrinarySize].Trytes()
-	t.Tag = trits[TagTrinaryOffset : TagTrinaryOffset+TagTrinarySize].Trytes()
-	t.AttachmentTimestamp = trits[AttachmentTimestampTrinaryOffset : AttachmentTimestampTrinaryOffset+AttachmentTimestampTrinarySize].Trytes()
-	t.AttachmentTimestampLowerBound = trits[AttachmentTimestampLowerBoundTrinaryOffset : AttachmentTimestampLowerBoundTrinaryOffset+AttachmentTimestampLowerBoundTrinarySize].Trytes()
-	t.AttachmentTimestampUpperBound = trits[AttachmentTimestampUpperBoundTrinaryOffset : AttachmentTimestampUpperBoundTrinaryOffset+AttachmentTimestampUpperBoundTrinarySize].Trytes()
-	t.Nonce = trits[NonceTrinaryOffset : NonceTrinaryOffset+NonceTrinarySize].Trytes()
 
+	p, err := l.Parse(trits)
+	if err != nil {
+		return err
+	}
+	*t = *p
 	return nil
 }
 
-// Trytes converts the transaction to Trytes.
+// Trytes converts the transaction to Trytes in the layout of its version.
+// They are empty if the version has no layout.
 func (t *Transaction) Trytes() Trytes {
-	tr := make(Trits, transactionTrinarySize)
-	copy(tr, t.SignatureMessageFragment.Trits())
-	copy(tr[AddressTrinaryOffset:], Trytes(t.Address).Trits())
-	copy(tr[ValueTrinaryOffset:], t.VectorP.Trits())
-	copy(tr[BlindingTrinaryOffset:], t.Value.Trits())
-	copy(tr[RangeProofTrinaryOffset:], t.RangeProof.Trits())
-	copy(tr[ObsoleteTagTrinaryOffset:], t.ObsoleteTag.Trits())
-	copy(tr[TimestampTrinaryOffset:], Int2Trits(t.Timestamp.Unix(), TimestampTrinarySize))
-	copy(tr[CurrentIndexTrinaryOffset:], Int2Trits(t.CurrentIndex, CurrentIndexTrinarySize))
-	copy(tr[LastIndexTrinaryOffset:], Int2Trits(t.LastIndex, LastIndexTrinarySize))
-	copy(tr[BundleTrinaryOffset:], t.Bundle.Trits())
-	copy(tr[TrunkTransactionTrinaryOffset:], t.TrunkTransaction.Trits())
-	copy(tr[BranchTransactionTrinaryOffset:], t.BranchTransaction.Trits())
-	copy(tr[TagTrinaryOffset:], t.Tag.Trits())
-	copy(tr[AttachmentTimestampTrinaryOffset:], t.AttachmentTimestamp.Trits())
-	copy(tr[AttachmentTimestampLowerBoundTrinaryOffset:], t.AttachmentTimestampLowerBound.Trits())
-	copy(tr[AttachmentTimestampUpperBoundTrinaryOffset:], t.AttachmentTimestampUpperBound.Trits())
-	copy(tr[NonceTrinaryOffset:], t.Nonce.Trits())
+	tr, err := t.trits()
+	if err != nil {
+		return ""
+	}
 	return tr.Trytes()
 }
 
+// trits returns the trits of the transaction in the layout of its version.
+func (t *Transaction) trits() (Trits, error) {
+	l, err := LayoutOf(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	return l.Trits(t), nil
+}
+
 // HasValidNonce checks if the transaction has the valid MinWeightMagnitude.
 // In order to check the MWM we count trailing 0's of the curlp hash of a
 // transaction.
@@ -216,7 +200,39 @@ func (t *Transaction) UnmarshalJSON(b []byte) error {
 	return t.parser(s.Trits())
 }
 
-// MarshalJSON makes trytes ([]byte) from a transaction.
+// MarshalBinary returns the trits of the transaction packed 5 per byte in
+// T5B1, about a fifth of the size of its trytes. It returns ErrUnknownLayout
+// if the version has no layout.
+func (t *Transaction) MarshalBinary() ([]byte, error) {
+	tr, err := t.trits()
+	if err != nil {
+		return nil, err
+	}
+	return tr.EncodeT5B1(), nil
+}
+
+// UnmarshalBinary makes the transaction from the bytes of MarshalBinary.
+func (t *Transaction) UnmarshalBinary(b []byte) error {
+	for _, size := range layoutSizes() {
+		if encodedLength(size, t5b1TritsPerByte) != len(b) {
+			continue
+		}
+
+		trits, err := DecodeT5B1(b, size)
+		if err != nil {
+			return err
+		}
+		return t.parser(trits)
+	}
+	return ErrInvalidTritsLength
+}
+
+// MarshalJSON makes trytes ([]byte) from a transaction. It returns
+// ErrUnknownLayout if the version has no layout.
 func (t *Transaction) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + t.Trytes() + `"`), nil
+	tr, err := t.trits()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(`"` + tr.Trytes() + `"`), nil
 }
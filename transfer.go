@@ -25,8 +25,8 @@ SOFTWARE.
 package giota
 
 import (
+	"context"
 	"errors"
-	"math"
 	"time"
 	"github.com/NebulousLabs/hdkey"
 	"log"
@@ -47,51 +47,89 @@ const DefaultNumberOfWalks = 5
 
 var waitGroup sync.WaitGroup
 
+// usedAddressWindow is the number of addresses GetUsedAddress looks up at once.
+const usedAddressWindow = 20
+
 // GetUsedAddress generates a new address which is not found in the tangle
-// and returns its new address and used addresses.
-func GetUsedAddress(api *API, seed Trytes) (Address, []Address, error) {
+// and returns its new address and used addresses. Addresses are looked up in
+// windows of usedAddressWindow with findTransactions requests only.
+func GetUsedAddress(api NodeAPI, seed Trytes) (Address, []Address, error) {
 	var all []Address
-	for index := 0; ; index++ {
-		adr, err := NewAddress(seed, index)
+	for index := 0; ; index += usedAddressWindow {
+		adrs, err := NewAddresses(seed, index, index+usedAddressWindow-1)
 		if err != nil {
 			return "", nil, err
 		}
 
-		r := FindTransactionsRequest{
-			Addresses: []Address{adr},
-		}
-
-		resp, err := api.FindTransactions(&r)
+		used, err := usedAddresses(api, adrs)
 		if err != nil {
 			return "", nil, err
 		}
 
-		if len(resp.Hashes) == 0 {
-			return adr, all, nil
+		for _, adr := range adrs {
+			if !used[adr] {
+				return adr, all, nil
+			}
+
+			// must be used address, repeat until return
+			all = append(all, adr)
 		}
+	}
+}
 
-		// reached the end of the loop, so must be used address, repeat until return
-		all = append(all, adr)
+// usedAddresses returns the addresses of adrs which have transactions in the
+// tangle. Batches of addresses with transactions are split in halves and
+// looked up again until the used addresses are found, so no trytes are
+// fetched.
+func usedAddresses(api NodeAPI, adrs []Address) (map[Address]bool, error) {
+	used := make(map[Address]bool)
+	var find func(adrs []Address) error
+	find = func(adrs []Address) error {
+		resp, err := api.FindTransactions(&FindTransactionsRequest{Addresses: adrs})
+		switch {
+		case err != nil:
+			return err
+		case len(resp.Hashes) == 0:
+			return nil
+		case len(adrs) == 1:
+			used[adrs[0]] = true
+			return nil
+		}
+
+		if err := find(adrs[:len(adrs)/2]); err != nil {
+			return err
+		}
+		return find(adrs[len(adrs)/2:])
+	}
+
+	if err := find(adrs); err != nil {
+		return nil, err
 	}
+	return used, nil
 }
 
 // GetInputs gets all possible inputs of a seed and returns them with the total balance.
-// end must be under start+500.
-func GetInputs(api *API, seed Trytes, start, end int, threshold int64) (Balances, error) {
-	var err error
-	var adrs []Address
-
+// end must be under start+500. If end is 0 the addresses of the seed are found
+// with ScanAddresses.
+func GetInputs(api NodeAPI, seed Trytes, start, end int, threshold int64) (Balances, error) {
 	if start > end || end > (start+500) {
 		return nil, errors.New("Invalid start/end provided")
 	}
 
-	switch {
-	case end > 0:
-		adrs, err = NewAddresses(seed, start, end-start)
-	default:
-		_, adrs, err = GetUsedAddress(api, seed)
+	if end == 0 {
+		k, err := NewKeyring(seed)
+		if err != nil {
+			return nil, err
+		}
+
+		report, err := ScanAddresses(api, k, DefaultGapLimit)
+		if err != nil {
+			return nil, err
+		}
+		return report.Balances, nil
 	}
 
+	adrs, err := NewAddresses(seed, start, end-start)
 	if err != nil {
 		return nil, err
 	}
@@ -103,8 +141,15 @@ func GetInputs(api *API, seed Trytes, start, end int, threshold int64) (Balances
 type Transfer struct {
 	Address Address
 	Value   int64
-	Message Trytes
 	Tag     Trytes
+
+	// Message is sent to the address over as many transactions as needed.
+	// Message.Encode returns the trytes of a typed message.
+	Message Trytes
+
+	// EncryptMessage seals Message to the key of the address, which opens it
+	// with Bundle.DecryptMessages.
+	EncryptMessage bool
 }
 
 const sigSize = SignatureMessageFragmentTrinarySize / 3
@@ -115,31 +160,11 @@ func addOutputs(secInt *big.Int, receiverPub *secp256k1.PublicKey, preProof *Pro
 		frags  []Trytes
 	)
 	for _, tr := range trs {
-		nsigs := 1
-
-		// If message longer than 2187 trytes, increase signatureMessageLength (add 2nd transaction)
-		switch {
-		case len(tr.Message) > sigSize:
-			// Get total length, message / maxLength (2187 trytes)
-			n := int(math.Floor(float64(len(tr.Message)) / sigSize))
-			nsigs += n
-
-			// While there is still a message, copy it
-			for k := 0; k < n; k++ {
-				var fragment Trytes
-				switch {
-				case k == n-1:
-					fragment = tr.Message[k*sigSize:]
-				default:
-					fragment = tr.Message[k*sigSize : (k+1)*sigSize]
-				}
-
-				// Pad remainder of fragment
-				frags = append(frags, fragment)
-			}
-		default:
-			frags = append(frags, tr.Message)
-		}
+		// A message longer than a fragment is split over as many
+		// transactions as needed, all sent to the address
+		msg := splitFragments(outputMessage(&tr))
+		frags = append(frags, msg...)
+		nsigs := len(msg)
 
 		// Add first entries to the bundle
 		// Slice the address in case the user provided a checksummed one
@@ -206,7 +231,7 @@ func (a *AddressInfo) Secret() (error){
 	return nil
 }
 
-func setupInputs(api *API, seed Trytes, inputs []AddressInfo, total int64) (Balances, []AddressInfo, error) {
+func setupInputs(api NodeAPI, seed Trytes, inputs []AddressInfo, total int64) (Balances, []AddressInfo, error) {
 	var bals Balances
 	var err error
 
@@ -254,7 +279,7 @@ func setupInputs(api *API, seed Trytes, inputs []AddressInfo, total int64) (Bala
 // PrepareTransfers gets an array of transfer objects as input, and then prepares
 // the transfer by generating the correct bundle as well as choosing and signing the
 // inputs if necessary (if it's a value transfer).
-func PrepareTransfers(api *API, seed Trytes, trs []Transfer, inputs []AddressInfo, remainder Address) (Bundle, error) {
+func PrepareTransfers(api NodeAPI, seed Trytes, trs []Transfer, inputs []AddressInfo, remainder Address) (Bundle, error) {
 	var err error
 	// TODO - change to be dynamic to allow smaller or larger sigs
 	var total int64 = 0
@@ -296,6 +321,9 @@ func PrepareTransfers(api *API, seed Trytes, trs []Transfer, inputs []AddressInf
 
 	if total <= 0 {
 		bundle.Finalize(frags)
+		if err = sealMessages(bundle, trs, senderSec); err != nil {
+			return nil, err
+		}
 		return bundle, nil
 	}
 
@@ -306,6 +334,9 @@ func PrepareTransfers(api *API, seed Trytes, trs []Transfer, inputs []AddressInf
 	}
 
 	bundle.Finalize(frags)
+	if err = sealMessages(bundle, trs, senderSec); err != nil {
+		return nil, err
+	}
 	err = signInputs(&preProof, inputs, bundle, seed)
 	return bundle, err
 }
@@ -317,7 +348,7 @@ func GenerateCommitment(receiverPub *secp256k1.PublicKey, secInt *big.Int, value
 	return comm
 }
 
-func addRemainder(receiverPub *secp256k1.PublicKey, secInt *big.Int, preProof *ProofPrep, api *API, in Balances, bundle *Bundle, remainder Address, seed Trytes, total int64) error {
+func addRemainder(receiverPub *secp256k1.PublicKey, secInt *big.Int, preProof *ProofPrep, api NodeAPI, in Balances, bundle *Bundle, remainder Address, seed Trytes, total int64) error {
 	for _, bal := range in {
 		var err error
 		val := big.NewInt(-bal.Value)
@@ -440,66 +471,107 @@ func signInputs(preProofs *ProofPrep, inputs []AddressInfo, bundle Bundle, seed
 	return nil
 }
 
-func doPow(tra *GetTransactionsToApproveResponse, depth int64, trytes []Transaction, mwm int64, pow PowFunc) error {
-	var prev Trytes
-	var err error
-	for i := len(trytes) - 1; i >= 0; i-- {
-		switch {
-		case i == len(trytes)-1:
-			trytes[i].TrunkTransaction = tra.TrunkTransaction
-			trytes[i].BranchTransaction = tra.BranchTransaction
-		default:
-			trytes[i].TrunkTransaction = prev
-			trytes[i].BranchTransaction = tra.TrunkTransaction
-		}
+// doPow chains the transactions to the transactions of tra and searches their
+// nonces with a BundlePow of e, the best engine if e is nil. progress is called
+// after every nonce found if it is not nil. The transactions attached are
+// copied to trytes even if it fails.
+func doPow(ctx context.Context, tra *GetTransactionsToApproveResponse, trytes []Transaction, mwm int64, e *PowEngine, progress func(PowProgress)) error {
+	if e == nil {
+		e = BestPowEngine()
+	}
+
+	b := NewBundlePow(e, trytes, tra.TrunkTransaction, tra.BranchTransaction, int(mwm))
+	b.Progress = progress
+	err := b.Run(ctx)
+	copy(trytes, b.Transactions())
+	return err
+}
 
-		timestamp := Int2Trits(time.Now().UnixNano()/1000000, TimestampTrinarySize).Trytes()
-		trytes[i].AttachmentTimestamp = timestamp
-		trytes[i].AttachmentTimestampLowerBound = ""
-		trytes[i].AttachmentTimestampUpperBound = maxTimestampTrytes
+// powFuncEngine returns the engine searching nonces with pow, or the best
+// engine if pow is nil.
+func powFuncEngine(pow PowFunc) *PowEngine {
+	if pow == nil {
+		return BestPowEngine()
+	}
+	return NewPowEngineFunc("PowFunc", func(_ context.Context, trytes Trytes, mwm int) (Trytes, error) {
+		return pow(trytes, mwm)
+	})
+}
 
-		trytes[i].Nonce, err = pow(trytes[i].Trytes(), int(mwm))
-		if err != nil {
-			return err
-		}
+// LocalAttachToTangle does the work of the AttachToTangle API with a local PowFunc,
+// or with the best PowEngine if pow is nil.
+// It chains the transactions to the trunk and branch transactions of att and
+// returns them with their nonces. att.Trytes is left unmodified.
+func LocalAttachToTangle(att *AttachToTangleRequest, pow PowFunc) (*AttachToTangleResponse, error) {
+	return LocalAttachToTangleContext(context.Background(), att, powFuncEngine(pow), nil)
+}
 
-		prev = trytes[i].Hash()
+// LocalAttachToTangleContext is LocalAttachToTangle searching the nonces with
+// e, the best engine if e is nil, until ctx is done. progress is called after
+// every nonce found if it is not nil.
+func LocalAttachToTangleContext(ctx context.Context, att *AttachToTangleRequest, e *PowEngine, progress func(PowProgress)) (*AttachToTangleResponse, error) {
+	tra := &GetTransactionsToApproveResponse{
+		TrunkTransaction:  att.TrunkTransaction,
+		BranchTransaction: att.BranchTransaction,
 	}
-	return nil
+
+	trytes := make([]Transaction, len(att.Trytes))
+	copy(trytes, att.Trytes)
+
+	if err := doPow(ctx, tra, trytes, att.MinWeightMagnitude, e, progress); err != nil {
+		return nil, err
+	}
+	return &AttachToTangleResponse{Trytes: trytes}, nil
 }
 
 // SendTrytes does attachToTangle and finally, it broadcasts the transactions.
-func SendTrytes(api *API, depth int64, trytes []Transaction, mwm int64, pow PowFunc) error {
+// The node does the PoW if pow is nil, otherwise it is done locally like
+// SendTrytesContext does.
+func SendTrytes(api NodeAPI, depth int64, trytes []Transaction, mwm int64, pow PowFunc) error {
+	if pow != nil {
+		return SendTrytesContext(context.Background(), api, depth, trytes, mwm, powFuncEngine(pow), nil)
+	}
+
 	tra, err := api.GetTransactionsToApprove(depth, DefaultNumberOfWalks, "")
 	if err != nil {
 		return err
 	}
 
-	switch {
-	case pow == nil:
-		at := AttachToTangleRequest{
-			TrunkTransaction:   tra.TrunkTransaction,
-			BranchTransaction:  tra.BranchTransaction,
-			MinWeightMagnitude: mwm,
-			Trytes:             trytes,
-		}
+	at := AttachToTangleRequest{
+		TrunkTransaction:   tra.TrunkTransaction,
+		BranchTransaction:  tra.BranchTransaction,
+		MinWeightMagnitude: mwm,
+		Trytes:             trytes,
+	}
 
-		// attach to tangle - do pow
-		attached, err := api.AttachToTangle(&at)
-		if err != nil {
-			return err
-		}
+	// attach to tangle - do pow
+	attached, err := api.AttachToTangle(&at)
+	if err != nil {
+		return err
+	}
+	return storeAndBroadcast(api, attached.Trytes)
+}
 
-		trytes = attached.Trytes
-	default:
-		err := doPow(tra, depth, trytes, mwm, pow)
-		if err != nil {
-			return err
-		}
+// SendTrytesContext attaches the transactions to the tangle with a local
+// BundlePow of e, the best engine if e is nil, and broadcasts them. progress
+// is called after every nonce found if it is not nil. It returns the error of
+// ctx if ctx is done before the PoW, trytes keeping the transactions attached.
+func SendTrytesContext(ctx context.Context, api NodeAPI, depth int64, trytes []Transaction, mwm int64, e *PowEngine, progress func(PowProgress)) error {
+	tra, err := api.GetTransactionsToApprove(depth, DefaultNumberOfWalks, "")
+	if err != nil {
+		return err
 	}
 
-	// Broadcast and store tx
-	err = api.StoreTransactions(trytes)
+	if err := doPow(ctx, tra, trytes, mwm, e, progress); err != nil {
+		return err
+	}
+	return storeAndBroadcast(api, trytes)
+}
+
+// storeAndBroadcast stores the transactions attached on the node and
+// broadcasts them.
+func storeAndBroadcast(api NodeAPI, trytes []Transaction) error {
+	err := api.StoreTransactions(trytes)
 	if err != nil {
 		return err
 	}
@@ -507,7 +579,7 @@ func SendTrytes(api *API, depth int64, trytes []Transaction, mwm int64, pow PowF
 }
 
 // Promote sends transanction using tail as reference (promotes the tail transaction)
-func Promote(api *API, tail Trytes, depth int64, trytes []Transaction, mwm int64, pow PowFunc) error {
+func Promote(api NodeAPI, tail Trytes, depth int64, trytes []Transaction, mwm int64, pow PowFunc) error {
 	if len(trytes) == 0 {
 		return errors.New("empty transfer")
 	}
@@ -540,7 +612,7 @@ func Promote(api *API, tail Trytes, depth int64, trytes []Transaction, mwm int64
 
 		trytes = attached.Trytes
 	default:
-		err := doPow(tra, depth, trytes, mwm, pow)
+		err := doPow(context.Background(), tra, trytes, mwm, powFuncEngine(pow), nil)
 		if err != nil {
 			return err
 		}
@@ -552,7 +624,7 @@ func Promote(api *API, tail Trytes, depth int64, trytes []Transaction, mwm int64
 
 // Send sends tokens. If you need to do pow locally, you must specifiy pow func,
 // otherwise this calls the AttachToTangle API
-func Send(api *API, seed Trytes, trs []Transfer, mwm int64, pow PowFunc) (Bundle, error) {
+func Send(api NodeAPI, seed Trytes, trs []Transfer, mwm int64, pow PowFunc) (Bundle, error) {
 	bd, err := PrepareTransfers(api, seed, trs, nil, "")
 	if err != nil {
 		return nil, err
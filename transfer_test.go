@@ -24,6 +24,7 @@ SOFTWARE.
 package giota
 
 import (
+	"context"
 	"testing"
 	"fmt"
 )
@@ -240,4 +241,75 @@ func TestApproveTransactions(t *testing.T) {
             t.Error(err)
         }
     }
-}
\ No newline at end of file
+}
+
+func TestSendTrytesContext(t *testing.T) {
+	api := &mockAPI{}
+	txs := testPowBundle(2)
+	var done []int
+	progress := func(p PowProgress) {
+		done = append(done, p.Done)
+	}
+
+	if err := SendTrytesContext(context.Background(), api, Depth, txs, 9, nil, progress); err != nil {
+		t.Fatal(err)
+	}
+	checkPowBundle(t, txs, EmptyHash, EmptyHash, 9)
+	if len(api.stored) != len(txs) || len(api.broadcasted) != len(txs) {
+		t.Errorf("SendTrytesContext() stored %d and broadcast %d transactions, expected %d",
+			len(api.stored), len(api.broadcasted), len(txs))
+	}
+	if len(done) != 2 || done[1] != 2 {
+		t.Errorf("progress reported %v", done)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	api = &mockAPI{}
+	err := SendTrytesContext(ctx, api, Depth, testPowBundle(2), 9, nil, nil)
+	if err != context.Canceled || len(api.stored) != 0 {
+		t.Errorf("SendTrytesContext() with a cancelled context returned %v", err)
+	}
+}
+
+// mockAPI is a NodeAPI which records the transactions that are stored and broadcast.
+type mockAPI struct {
+	NodeAPI
+	stored      []Transaction
+	broadcasted []Transaction
+}
+
+func (m *mockAPI) GetTransactionsToApprove(depth, numWalks int64, reference Trytes) (*GetTransactionsToApproveResponse, error) {
+	return &GetTransactionsToApproveResponse{
+		TrunkTransaction:  EmptyHash,
+		BranchTransaction: EmptyHash,
+	}, nil
+}
+
+func (m *mockAPI) AttachToTangle(att *AttachToTangleRequest) (*AttachToTangleResponse, error) {
+	return &AttachToTangleResponse{Trytes: att.Trytes}, nil
+}
+
+func (m *mockAPI) StoreTransactions(trytes []Transaction) error {
+	m.stored = append(m.stored, trytes...)
+	return nil
+}
+
+func (m *mockAPI) BroadcastTransactions(trytes []Transaction) error {
+	m.broadcasted = append(m.broadcasted, trytes...)
+	return nil
+}
+
+func TestSendTrytesNodeAPI(t *testing.T) {
+	api := &mockAPI{}
+	txs := []Transaction{{Address: EmptyAddress}, {Address: EmptyAddress}}
+
+	if err := SendTrytes(api, Depth, txs, DefaultMinWeightMagnitude, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.stored) != len(txs) || len(api.broadcasted) != len(txs) {
+		t.Errorf("SendTrytes() stored %d and broadcast %d transactions, expected %d",
+			len(api.stored), len(api.broadcasted), len(txs))
+	}
+}
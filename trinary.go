@@ -28,8 +28,8 @@ package giota
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strings"
-	"unsafe"
 )
 
 var (
@@ -155,200 +155,66 @@ const (
 	IntLength      = ByteLength / 4
 )
 
-// 3^(242/2)
-// 12 * 32 bit
-var halfThree = []uint32{
-	0xa5ce8964,
-	0x9f007669,
-	0x1484504f,
-	0x3ade00d9,
-	0x0c24486e,
-	0x50979d57,
-	0x79a4c702,
-	0x48bbae36,
-	0xa9f6808b,
-	0xaa06a805,
-	0xa87fabdf,
-	0x5e69ebef,
-}
+var (
+	// threeToHashLength is 3^242, the number of values of the trits of a
+	// hash converted to bytes, its last trit being always 0.
+	threeToHashLength = new(big.Int).Exp(bigThree, big.NewInt(TritHashLength-1), nil)
+
+	// halfThree is (3^242-1)/2, the largest of these values.
+	halfThree = new(big.Int).Rsh(threeToHashLength, 1)
+
+	// twoToByteLength is 2^384, the number of values of ByteLength bytes.
+	twoToByteLength = new(big.Int).Lsh(bigOne, ByteLength*8)
+)
 
 // IsValidLength returns the validity of the trit length
 func (t Trits) IsValidLength() bool {
 	return len(t) != TritHashLength
 }
 
-// Bytes is only defined for hashes, i.e. slices of trits of length 243. It returns 48 bytes.
-// nolint: gocyclo, gas
+// Bytes is only defined for hashes, i.e. slices of trits of length 243. It returns 48 bytes,
+// the value of the trits but the last one in big-endian two's complement.
 func (t Trits) Bytes() ([]byte, error) {
 	if t.IsValidLength() {
 		return nil, fmt.Errorf("Bytes() is only defined for trit slices of length %d", TritHashLength)
 	}
 
-	allNeg := true
-	for _, e := range t[0 : TritHashLength-1] { // Last position should be always zero.
-		if e != -1 {
-			allNeg = false
-			break
-		}
-	}
-
-	// Trit to BigInt
-	b := make([]byte, 48) // 48 bytes/384 bits
-
-	// 12 * 32 bits = 384 bits
-	base := (*(*[]uint32)(unsafe.Pointer(&b)))[0:IntLength]
-
-	if allNeg {
-		// If all trits are -1 then we're half way through all the numbers,
-		// since they're in two's complement notation.
-		copy(base, halfThree)
-
-		// Compensate for setting the last position to zero.
-		bigIntNot(base)
-		bigIntAddSmall(base, 1)
-
-		return reverse(b), nil
-	}
-
-	revT := make([]int8, len(t))
-	copy(revT, t)
-	size := 1
-
-	for _, e := range reverseT(revT[0 : TritHashLength-1]) {
-		sz := size
-		var carry uint32
-		for j := 0; j < sz; j++ {
-			v := uint64(base[j])*uint64(Radix) + uint64(carry)
-			carry = uint32(v >> 32)
-			base[j] = uint32(v)
-		}
-
-		if carry > 0 {
-			base[sz] = carry
-			size = size + 1
-		}
-
-		trit := uint32(e + 1)
-
-		ns := bigIntAddSmall(base, trit)
-		if ns > size {
-			size = ns
-		}
+	v := TernaryFromTrits(t[:TritHashLength-1]).Big()
+	if v.Sign() < 0 {
+		v.Add(v, twoToByteLength)
 	}
 
-	if !bigIntIsNull(base) {
-		if bigIntCmp(halfThree, base) <= 0 {
-			// base >= HALF_3
-			// just do base - HALF_3
-			bigIntSub(base, halfThree)
-		} else {
-			// we don't have a wrapping sub.
-			// so let's use some bit magic to achieve it
-			tmp := make([]uint32, IntLength)
-			copy(tmp, halfThree)
-			bigIntSub(tmp, base)
-			bigIntNot(tmp)
-			bigIntAddSmall(tmp, 1)
-			copy(base, tmp)
-		}
-	}
-	return reverse(b), nil
+	b := make([]byte, ByteLength)
+	vb := v.Bytes()
+	copy(b[ByteLength-len(vb):], vb)
+	return b, nil
 }
 
 // JavaTrits - this is a mirror of the trits to bytes implementation from the iri
+//
+// Deprecated: JavaTrits is EncodeT5B1.
 func (t Trits) JavaTrits() []byte {
-	const NUMBER_TRITS = 5
-	expectedLength := (len(t) + NUMBER_TRITS-1) / NUMBER_TRITS;
-	output := make([]byte, expectedLength)
-	for i := 0; i < expectedLength; i++  {
-		value := 0
-		var rounds int
-		if len(t) - (i * NUMBER_TRITS) < 5 {
-			rounds = len(t) - (i * NUMBER_TRITS)
-		} else {
-			rounds = NUMBER_TRITS
-		}
-		for j := rounds - 1; j > 0 ; j--  {
-			value = value * 3 + int(t[i * NUMBER_TRITS + j])
-		}
-		output[i] = byte(value)
-
-	}
-	return output
+	return t.EncodeT5B1()
 }
 
-// BytesToTrits converts binary to ternay
+// BytesToTrits converts binary to ternay. The bytes are a big-endian two's
+// complement integer, which is reduced into the values of 242 trits and the
+// last trit is 0.
 func BytesToTrits(b []byte) (Trits, error) {
 	if len(b) != ByteLength {
 		return nil, fmt.Errorf("BytesToTrits() is only defined for byte slices of length %d", ByteLength)
 	}
 
-	rb := make([]byte, len(b))
-	copy(rb, b)
-	reverse(rb)
-
-	t := Trits(make([]int8, TritHashLength))
-	t[TritHashLength-1] = 0
-
-	// nolint: gas
-	base := (*(*[]uint32)(unsafe.Pointer(&rb)))[0:IntLength] // 12 * 32 bits = 384 bits
-
-	if bigIntIsNull(base) {
-		return t, nil
+	v := new(big.Int).SetBytes(b)
+	if b[0]&0x80 != 0 {
+		v.Sub(v, twoToByteLength)
 	}
 
-	var flipTrits bool
-
-	// Check if the MSB is 0, i.e. we have a positive number
-	// nolint: gas
-	msbM := (unsafe.Sizeof(base[IntLength-1]) * 8) - 1
-
-	switch {
-	case base[IntLength-1]>>msbM == 0:
-		bigIntAdd(base, halfThree)
-	default:
-		bigIntNot(base)
-		if bigIntCmp(base, halfThree) == 1 {
-			bigIntSub(base, halfThree)
-			flipTrits = true
-		} else {
-			bigIntAddSmall(base, 1)
-			tmp := make([]uint32, IntLength)
-			copy(tmp, halfThree)
-			bigIntSub(tmp, base)
-			copy(base, tmp)
-		}
+	v.Mod(v, threeToHashLength)
+	if v.Cmp(halfThree) > 0 {
+		v.Sub(v, threeToHashLength)
 	}
-
-	var rem uint64
-	for i := range t[0 : TritHashLength-1] {
-		rem = 0
-		for j := IntLength - 1; j >= 0; j-- {
-			lhs := (rem << 32) | uint64(base[j])
-			rhs := uint64(Radix)
-			q := uint32(lhs / rhs)
-			r := uint32(lhs % rhs)
-			base[j] = q
-			rem = uint64(r)
-		}
-		t[i] = int8(rem) - 1
-	}
-
-	if flipTrits {
-		for i := range t {
-			t[i] = -t[i]
-		}
-	}
-
-	return t, nil
-}
-
-func reverseT(a Trits) Trits {
-	for left, right := 0, len(a)-1; left < right; left, right = left+1, right-1 {
-		a[left], a[right] = a[right], a[left]
-	}
-
-	return a
+	return TernaryFromBig(v).PaddedTrits(TritHashLength)
 }
 
 // Trytes is a string of trytes. You should not typecast, use ToTrytes instead to be safe
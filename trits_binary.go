@@ -0,0 +1,102 @@
+package giota
+
+import "errors"
+
+// T5B1 packs 5 trits in a byte and T3B1 3 trits, a tryte, in a byte. The
+// trits of a byte are its value in balanced ternary, the first trit being
+// the least significant, stored as a signed byte. The last byte is padded
+// with 0 trits.
+const (
+	t5b1TritsPerByte = 5
+	t3b1TritsPerByte = 3
+)
+
+// errors of binary encodings
+var (
+	ErrInvalidTritsLength = errors.New("trits length doesn't match the encoded bytes")
+	ErrInvalidTritsByte   = errors.New("invalid byte of encoded trits")
+)
+
+// EncodeT5B1 returns t packed 5 trits per byte.
+func (t Trits) EncodeT5B1() []byte {
+	return encodeTrits(t, t5b1TritsPerByte)
+}
+
+// EncodeT3B1 returns t packed 3 trits per byte.
+func (t Trits) EncodeT3B1() []byte {
+	return encodeTrits(t, t3b1TritsPerByte)
+}
+
+// DecodeT5B1 returns the n trits packed 5 per byte in b.
+func DecodeT5B1(b []byte, n int) (Trits, error) {
+	return decodeTrits(b, n, t5b1TritsPerByte)
+}
+
+// DecodeT3B1 returns the n trits packed 3 per byte in b.
+func DecodeT3B1(b []byte, n int) (Trits, error) {
+	return decodeTrits(b, n, t3b1TritsPerByte)
+}
+
+// encodedLength returns the number of bytes of n trits packed per in a byte.
+func encodedLength(n, per int) int {
+	return (n + per - 1) / per
+}
+
+func encodeTrits(t Trits, per int) []byte {
+	b := make([]byte, encodedLength(len(t), per))
+	for i := range b {
+		end := (i + 1) * per
+		if end > len(t) {
+			end = len(t)
+		}
+
+		var v int8
+		for j := end - 1; j >= i*per; j-- {
+			v = v*3 + t[j]
+		}
+		b[i] = byte(v)
+	}
+	return b
+}
+
+func decodeTrits(b []byte, n, per int) (Trits, error) {
+	if n < 0 || encodedLength(n, per) != len(b) {
+		return nil, ErrInvalidTritsLength
+	}
+
+	// the largest value of a byte is 1+3+...+3^(per-1)
+	max := 0
+	for i, p := 0, 1; i < per; i, p = i+1, p*3 {
+		max += p
+	}
+
+	t := make(Trits, len(b)*per)
+	for i, c := range b {
+		v := int(int8(c))
+		if v > max || v < -max {
+			return nil, ErrInvalidTritsByte
+		}
+
+		for j := 0; j < per; j++ {
+			r := v % 3
+			v /= 3
+			switch r {
+			case 2:
+				r = -1
+				v++
+			case -2:
+				r = 1
+				v--
+			}
+			t[i*per+j] = int8(r)
+		}
+	}
+
+	// padding trits must be 0
+	for _, r := range t[n:] {
+		if r != 0 {
+			return nil, ErrInvalidTritsByte
+		}
+	}
+	return t[:n], nil
+}
@@ -0,0 +1,84 @@
+package giota
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestT5B1(t *testing.T) {
+	tests := []struct {
+		trits Trits
+		bytes []byte
+	}{
+		{Trits{}, []byte{}},
+		{Trits{1}, []byte{1}},
+		{Trits{-1, 1}, []byte{2}},
+		{Trits{1, 1, 1, 1, 1}, []byte{121}},
+		{Trits{-1, -1, -1, -1, -1}, []byte{byte(256 - 121)}},
+		{Trits{0, 0, 0, 0, 1, -1}, []byte{81, 255}},
+	}
+	for _, tt := range tests {
+		if b := tt.trits.EncodeT5B1(); !bytes.Equal(b, tt.bytes) {
+			t.Errorf("EncodeT5B1(%v) = %v, want %v", tt.trits, b, tt.bytes)
+		}
+		tr, err := DecodeT5B1(tt.bytes, len(tt.trits))
+		if err != nil || !tr.Equal(tt.trits) {
+			t.Errorf("DecodeT5B1(%v) = %v, %v, want %v", tt.bytes, tr, err, tt.trits)
+		}
+	}
+
+	if _, err := DecodeT5B1([]byte{122}, 5); err != ErrInvalidTritsByte {
+		t.Errorf("DecodeT5B1() of an invalid byte returned %v", err)
+	}
+	if _, err := DecodeT5B1([]byte{1, 1}, 5); err != ErrInvalidTritsLength {
+		t.Errorf("DecodeT5B1() of too many bytes returned %v", err)
+	}
+	if _, err := DecodeT5B1([]byte{81}, 4); err != ErrInvalidTritsByte {
+		t.Errorf("DecodeT5B1() with non zero padding returned %v", err)
+	}
+}
+
+func TestT3B1(t *testing.T) {
+	tr := Trytes("ABCXYZ9NOPQ").Trits()
+	b := tr.EncodeT3B1()
+	if len(b) != len(tr)/3 {
+		t.Fatalf("EncodeT3B1() returned %d bytes for %d trytes", len(b), len(tr)/3)
+	}
+	if b[0] != 1 || b[6] != 0 || int8(b[7]) != -13 {
+		t.Errorf("EncodeT3B1() = %v", b)
+	}
+
+	dec, err := DecodeT3B1(b, len(tr))
+	if err != nil || !dec.Equal(tr) {
+		t.Errorf("DecodeT3B1() = %v, %v", dec, err)
+	}
+	if _, err := DecodeT3B1([]byte{14}, 3); err != ErrInvalidTritsByte {
+		t.Errorf("DecodeT3B1() of an invalid byte returned %v", err)
+	}
+}
+
+func TestTransactionBinary(t *testing.T) {
+	tx := testPowTransaction()
+	tx.Value = Trytes("ABC")
+	tx.Nonce = Trytes("NONCE")
+
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != (transactionTrinarySize+4)/5 {
+		t.Errorf("MarshalBinary() returned %d bytes", len(b))
+	}
+
+	var got Transaction
+	if err = got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.Trytes() != tx.Trytes() {
+		t.Error("the unmarshaled transaction differs")
+	}
+
+	if err = got.UnmarshalBinary(b[1:]); err == nil {
+		t.Error("UnmarshalBinary() of truncated bytes succeeded")
+	}
+}